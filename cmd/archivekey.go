@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/steps"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+var (
+	archiveKeyDestination  string
+	archiveKeyReleaseImage string
+)
+
+var archiveSigningKeyCmd = &cobra.Command{
+	Use:   "archive-signing-key",
+	Short: "Archive the service-account signing key pair outside the workspace",
+	Long: `Copies the key pair generated by ccoctl in Step 7 to a directory outside the
+workspace, readable only by the current user, so it can be reused with the
+signingKey option when the cluster is reinstalled`,
+	Run: runArchiveSigningKey,
+}
+
+func init() {
+	rootCmd.AddCommand(archiveSigningKeyCmd)
+
+	archiveSigningKeyCmd.Flags().StringVar(&archiveKeyDestination, "destination", "", "Directory outside the workspace to archive the key pair to")
+	archiveSigningKeyCmd.Flags().StringVar(&archiveKeyReleaseImage, "release-image", "", "OpenShift release image (to find correct version directory)")
+	archiveSigningKeyCmd.MarkFlagRequired("destination")
+}
+
+func runArchiveSigningKey(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+	cfg := loadConfig(log)
+	if archiveKeyReleaseImage != "" {
+		cfg.ReleaseImage = archiveKeyReleaseImage
+	}
+
+	// Same output directory as the install command
+	if cfg.OutputDir == "_output" {
+		versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+		if err != nil {
+			log.Error(fmt.Sprintf("A release image is required to find the output directory: %v", err))
+			os.Exit(1)
+		}
+		cfg.OutputDir = filepath.Join("artifacts", versionArch, "_output")
+	}
+
+	archived, err := steps.ArchiveSigningKey(cfg.OutputDir, archiveKeyDestination)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to archive signing key: %v", err))
+		os.Exit(1)
+	}
+
+	log.Info(fmt.Sprintf("✓ Signing key pair archived to %s", archiveKeyDestination))
+	log.Info("Reuse it on the next install with:")
+	fmt.Printf("signingKey:\n  privateKey: %s\n  publicKey: %s\n", archived[0], archived[1])
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -16,23 +17,41 @@ var (
 	cleanupClusterName  string
 	cleanupAwsRegion    string
 	cleanupReleaseImage string
+	cleanupWorkspace    string
+	cleanupYes          bool
+	cleanupDryRun       bool
+	cleanupVerify       bool
+	cleanupPurgeLocal   bool
+	cleanupArchive      string
 )
 
+// Exit code of cleanup --dry-run when no resource was found
+const exitNothingFound = 2
+
 var cleanupCmd = &cobra.Command{
-	Use:   "cleanup",
+	Use:   "cleanup [cluster]",
 	Short: "Clean up AWS resources after a failed installation",
-	Long:  `Removes AWS resources (infrastructure, DNS, S3 bucket, IAM roles) created during installation`,
-	Run:   runCleanup,
+	Long: `Removes AWS resources (infrastructure, DNS, S3 bucket, IAM roles) created during installation.
+
+Cluster name, region, infraID and binaries are discovered from the workspace
+(install-config.yaml or its backup, metadata.json and inventory.json). Pass the
+cluster name when the workspace holds more than one installation.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runCleanup,
 }
 
 func init() {
 	rootCmd.AddCommand(cleanupCmd)
 
-	cleanupCmd.Flags().StringVar(&cleanupClusterName, "cluster-name", "", "Cluster/infrastructure name")
-	cleanupCmd.Flags().StringVar(&cleanupAwsRegion, "region", "", "AWS region")
-	cleanupCmd.Flags().StringVar(&cleanupReleaseImage, "release-image", "", "OpenShift release image (to find correct version directory)")
-	cleanupCmd.MarkFlagRequired("cluster-name")
-	cleanupCmd.MarkFlagRequired("region")
+	cleanupCmd.Flags().StringVar(&cleanupClusterName, "cluster-name", "", "Cluster/infrastructure name (default: discovered from the workspace)")
+	cleanupCmd.Flags().StringVar(&cleanupAwsRegion, "region", "", "AWS region (default: discovered from the workspace)")
+	cleanupCmd.Flags().StringVar(&cleanupReleaseImage, "release-image", "", "OpenShift release image (to find correct version directory, default: discovered from the workspace)")
+	cleanupCmd.Flags().StringVar(&cleanupWorkspace, "workspace", ".", "Workspace directory of the installation")
+	cleanupCmd.Flags().BoolVarP(&cleanupYes, "yes", "y", false, "Do not ask for confirmation")
+	cleanupCmd.Flags().BoolVar(&cleanupVerify, "verify", false, "After cleanup, query AWS for resources left behind")
+	cleanupCmd.Flags().BoolVar(&cleanupPurgeLocal, "purge-local", false, "Securely delete the local installation files (keys, kubeconfig, manifests) after cleanup")
+	cleanupCmd.Flags().StringVar(&cleanupArchive, "archive", "", "Save the installer log, install-config backup, metadata.json and ccoctl manifests to this tar.gz first")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "List the resources that would be removed without deleting anything (exit code 2 if none)")
 }
 
 func runCleanup(cmd *cobra.Command, args []string) {
@@ -54,91 +73,332 @@ func runCleanup(cmd *cobra.Command, args []string) {
 	}
 	cfg.SetDefaults()
 
+	clusterName := cleanupClusterName
+	if len(args) == 1 {
+		clusterName = args[0]
+	}
+	installation, inventory, err := findCleanupTarget(log, clusterName)
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+
+	plan, err := installation.CleanupPlan(inventory, cfg.BYOIAM == nil)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to list resources: %v", err))
+		os.Exit(1)
+	}
+	if cleanupDryRun {
+		printCleanupPlan(installation, plan)
+		if cleanupPurgeLocal {
+			local, err := installation.LocalFiles()
+			if err != nil {
+				log.Error(fmt.Sprintf("Failed to list local files: %v", err))
+				os.Exit(1)
+			}
+			for _, path := range local {
+				fmt.Printf("  Local files: %s\n", path)
+			}
+			reportSharedWorkspace(log, installation)
+		}
+		if plan.Empty() {
+			os.Exit(exitNothingFound)
+		}
+		return
+	}
+
+	if cleanupVerify {
+		if err := config.CheckAWSCLI("cleanup --verify"); err != nil {
+			log.Error(fmt.Sprintf("Prerequisite check failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
 	// Validate AWS credentials before proceeding
-	log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", cfg.AwsProfile))
-	if err := util.ValidateAWSCredentials(cfg.AwsProfile); err != nil {
+	if _, err := validateAWSProfiles(log, cfg); err != nil {
 		log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
 		os.Exit(1)
 	}
-	log.Info("✓ AWS credentials are valid")
+
+	// destroy runs with the infra profile, ccoctl aws delete with the IAM one
+	infraEnv, err := profileEnv(cfg, cfg.InfraProfile())
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	iamEnv, err := profileEnv(cfg, cfg.IAMProfile())
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
 
 	// Confirm with user
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("This will delete AWS resources for cluster '%s' in region '%s'.\n", cleanupClusterName, cleanupAwsRegion)
-	fmt.Print("Continue? (y/n): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response != "y" && response != "yes" {
-		log.Info("Cleanup cancelled.")
-		return
+	if !cleanupYes {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf("This will delete AWS resources for cluster '%s' in region '%s'.\n", installation.ClusterName, installation.Region)
+		if installation.InfraID != "" {
+			fmt.Printf("Infrastructure ID: %s\n", installation.InfraID)
+		}
+		if installation.PrivateBucket {
+			fmt.Printf("OIDC bucket: %s (private, served by CloudFront)\n", util.OIDCBucketName(installation.ClusterName))
+		}
+		fmt.Print("Continue? (y/n): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+
+		if response != "y" && response != "yes" {
+			log.Info("Cleanup cancelled.")
+			return
+		}
 	}
 
 	executor := &util.RealExecutor{}
 
-	// Step 1: Run openshift-install destroy if we have the version directory
-	if cleanupReleaseImage != "" {
-		versionArch, err := util.ExtractVersionArch(cleanupReleaseImage)
+	// Archive before destroy, which removes metadata.json
+	if cleanupArchive != "" {
+		archived, err := installation.ArchiveLocalFiles(cleanupArchive)
 		if err != nil {
-			log.Error(fmt.Sprintf("Failed to extract version from release image: %v", err))
+			log.Error(fmt.Sprintf("Failed to archive the installation: %v", err))
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Archived %d files to %s", len(archived), cleanupArchive))
+	}
+
+	// Step 1: Run openshift-install destroy if the installation created infrastructure
+	infraLeft := false
+	if installation.VersionArch == "" {
+		log.Info("No installation directory found, skipping openshift-install destroy")
+		log.Info("If you have orphaned infrastructure, run: ./artifacts/<version>/bin/openshift-install destroy cluster --dir artifacts/<version>/")
+	} else if installation.HasInfrastructure() {
+		log.StartStep("Destroying OpenShift infrastructure")
+
+		destroyArgs := []string{"destroy", "cluster", "--dir", installation.VersionDir(), "--log-level=debug"}
+
+		if err := executor.ExecuteInteractiveWithEnv(installation.Binary("openshift-install"), infraEnv, destroyArgs...); err != nil {
+			log.FailStep("Destroy infrastructure")
+			log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
+			log.Info("Continuing with ccoctl cleanup...")
+			infraLeft = true
 		} else {
-			versionDir := fmt.Sprintf("artifacts/%s", versionArch)
-			stateFile := fmt.Sprintf("%s/.openshift_install_state.json", versionDir)
-			installBin := util.GetBinaryPath(versionArch, "openshift-install")
-
-			// Check if state file exists
-			if util.FileExists(stateFile) {
-				log.StartStep("Destroying OpenShift infrastructure")
-
-				destroyArgs := []string{"destroy", "cluster", "--dir", versionDir, "--log-level=debug"}
-
-				if err := executor.ExecuteInteractive(installBin, destroyArgs...); err != nil {
-					log.FailStep("Destroy infrastructure")
-					log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
-					log.Info("Continuing with ccoctl cleanup...")
-				} else {
-					log.CompleteStep("Destroy infrastructure")
-				}
-			} else {
-				log.Info(fmt.Sprintf("No state file found at %s, skipping openshift-install destroy", stateFile))
-			}
+			log.CompleteStep("Destroy infrastructure")
 		}
 	} else {
-		log.Info("No --release-image provided, skipping openshift-install destroy")
-		log.Info("If you have orphaned infrastructure, run: ./artifacts/<version>/bin/openshift-install destroy cluster --dir artifacts/<version>/")
+		log.Info(fmt.Sprintf("No metadata.json or state file found in %s, skipping openshift-install destroy", installation.VersionDir()))
 	}
 
-	// Step 2: Run ccoctl aws delete to clean up IAM roles and S3 bucket
-	log.StartStep("Cleaning up IAM roles and S3 bucket")
+	// IAM roles and OIDC issuer provided in the config are not ours to delete
+	failed := false
+	if cfg.BYOIAM != nil {
+		log.Info("IAM roles and OIDC issuer are managed outside the installer (byoIAM), skipping ccoctl cleanup")
+	} else {
+		// Step 2: Run ccoctl aws delete to clean up IAM roles and S3 bucket
+		log.StartStep("Cleaning up IAM roles and S3 bucket")
 
-	// Find ccoctl binary
-	ccoctlPath := "ccoctl"
-	if cleanupReleaseImage != "" {
-		versionArch, err := util.ExtractVersionArch(cleanupReleaseImage)
-		if err == nil {
-			versionCcoctl := util.GetBinaryPath(versionArch, "ccoctl")
-			if util.FileExists(versionCcoctl) {
-				ccoctlPath = versionCcoctl
-			}
+		args_cleanup := []string{
+			"aws", "delete",
+			"--name", installation.ClusterName,
+			"--region", installation.Region,
+		}
+
+		if err := util.RunCommandWithEnv(executor, iamEnv, installation.Binary("ccoctl"), args_cleanup...); err != nil {
+			log.FailStep("Cleanup IAM/S3")
+			log.Error(fmt.Sprintf("Failed to clean up IAM/S3: %v", err))
+			log.Info("You may need to manually delete AWS resources.")
+			failed = true
+		} else {
+			log.CompleteStep("Cleanup IAM/S3")
 		}
 	}
-	if ccoctlPath == "ccoctl" && util.FileExists("artifacts/bin/ccoctl") {
-		ccoctlPath = "artifacts/bin/ccoctl"
+
+	// Step 3: Look for anything the previous steps left behind
+	if cleanupVerify {
+		if !verifyCleanup(log, cfg, executor, iamEnv, infraEnv, installation) {
+			failed = true
+		}
 	}
 
-	args_cleanup := []string{
-		"aws", "delete",
-		"--name", cleanupClusterName,
-		"--region", cleanupAwsRegion,
+	if failed {
+		if cleanupPurgeLocal {
+			log.Info("Keeping the local files, they are needed to retry the cleanup")
+		}
+		os.Exit(1)
 	}
+	log.Info("All AWS resources have been deleted.")
 
-	if err := util.RunCommand(executor, ccoctlPath, args_cleanup...); err != nil {
-		log.FailStep("Cleanup IAM/S3")
-		log.Error(fmt.Sprintf("Failed to clean up IAM/S3: %v", err))
-		log.Info("You may need to manually delete AWS resources.")
+	// The inventory would attribute the deleted resources to a reinstall
+	if infraLeft {
+		log.Info(fmt.Sprintf("Keeping %s, the infrastructure was not destroyed", util.InventoryFile))
+	} else if err := util.RemoveInventory(filepath.Join(cleanupWorkspace, util.InventoryFile), installation); err != nil {
+		log.Error(err.Error())
 		os.Exit(1)
 	}
 
-	log.CompleteStep("Cleanup IAM/S3")
-	log.Info("All AWS resources have been deleted.")
+	// Step 4: Remove keys, kubeconfig and manifests left on disk
+	if cleanupPurgeLocal {
+		removed, err := installation.PurgeLocalFiles()
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to purge local files: %v", err))
+			os.Exit(1)
+		}
+		for _, path := range removed {
+			log.Debug(fmt.Sprintf("Removed %s", path))
+		}
+		log.Info(fmt.Sprintf("Purged %d local paths, binaries in %s were kept", len(removed), filepath.Join(installation.VersionDir(), "bin")))
+		reportSharedWorkspace(log, installation)
+	}
+}
+
+// reportSharedWorkspace tells which workspace-level directories the purge
+// keeps because other installations of the workspace may need them
+func reportSharedWorkspace(log *logger.Logger, installation *util.Installation) {
+	skipped, err := installation.SkippedWorkspaceDirs()
+	if err != nil || len(skipped) == 0 {
+		return
+	}
+	log.Info(fmt.Sprintf("The workspace holds other installations, keeping %s; remove them once no installation needs them",
+		strings.Join(skipped, ", ")))
+}
+
+// verifyCleanup queries AWS for resources of the installation still present
+// and prints the commands removing them. It returns false when any was found.
+func verifyCleanup(log *logger.Logger, cfg *config.Config, executor util.CommandExecutor, iamEnv, infraEnv []string,
+	installation *util.Installation) bool {
+	log.StartStep("Verifying that no resources are left")
+
+	region := "AWS_REGION=" + installation.Region
+	iamEnv = append(iamEnv, region)
+	infraEnv = append(infraEnv, region)
+
+	if installation.InfraID == "" {
+		log.Info("infraID unknown, skipping the search for resources tagged with it")
+	}
+	orphans, err := util.FindOrphans(executor, iamEnv, infraEnv, installation, cfg.BYOIAM == nil)
+	if err != nil {
+		log.FailStep("Verify cleanup")
+		log.Error(fmt.Sprintf("Failed to look for leftover resources: %v", err))
+		return false
+	}
+	if len(orphans) == 0 {
+		log.CompleteStep("Verify cleanup")
+		return true
+	}
+
+	log.FailStep("Verify cleanup")
+	log.Error(fmt.Sprintf("%d resources were left behind:", len(orphans)))
+	printed := map[string]bool{}
+	for _, orphan := range orphans {
+		fmt.Printf("  %s: %s\n", orphan.Kind, orphan.ID)
+	}
+	fmt.Println("\nRemove them with:")
+	for _, orphan := range orphans {
+		for _, command := range orphan.Remove {
+			if printed[command] {
+				continue
+			}
+			printed[command] = true
+			fmt.Printf("  %s\n", command)
+		}
+	}
+	return false
+}
+
+// findCleanupTarget picks the installation to clean up. Values given on the
+// command line win over the workspace, which wins over inventory.json.
+func findCleanupTarget(log *logger.Logger, clusterName string) (*util.Installation, *util.Inventory, error) {
+	versionArch := ""
+	if cleanupReleaseImage != "" {
+		va, err := util.ExtractVersionArch(cleanupReleaseImage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to extract version from release image: %w", err)
+		}
+		versionArch = va
+	}
+
+	inventory, err := util.LoadInventory(filepath.Join(cleanupWorkspace, util.InventoryFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	if versionArch == "" && inventory.ReleaseImage != "" && (clusterName == "" || clusterName == inventory.ClusterName) {
+		versionArch, _ = util.ExtractVersionArch(inventory.ReleaseImage)
+	}
+
+	installations, err := util.FindInstallations(cleanupWorkspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var matches []*util.Installation
+	for _, installation := range installations {
+		if clusterName != "" && installation.ClusterName != clusterName {
+			continue
+		}
+		if versionArch != "" && installation.VersionArch != versionArch {
+			continue
+		}
+		matches = append(matches, installation)
+	}
+
+	var target *util.Installation
+	switch len(matches) {
+	case 0:
+		target = &util.Installation{Workspace: cleanupWorkspace, VersionArch: versionArch, ClusterName: clusterName}
+		if target.ClusterName == "" {
+			target.ClusterName = inventory.ClusterName
+		}
+		if inventory.RecordsInstallation(target) {
+			target.Region = inventory.Region
+			target.InfraID = inventory.InfraID
+		}
+	case 1:
+		target = matches[0]
+		log.Debug(fmt.Sprintf("Found installation of cluster %s in %s", target.ClusterName, target.VersionDir()))
+	default:
+		var found []string
+		for _, m := range matches {
+			found = append(found, fmt.Sprintf("%s (%s)", m.ClusterName, m.VersionArch))
+		}
+		return nil, nil, fmt.Errorf("the workspace holds several installations: %s; pass the cluster name or --release-image", strings.Join(found, ", "))
+	}
+
+	if cleanupAwsRegion != "" {
+		target.Region = cleanupAwsRegion
+	}
+	if target.InfraID == "" && inventory.RecordsInstallation(target) {
+		target.InfraID = inventory.InfraID
+	}
+	if inventory.CloudFrontDistribution != "" && inventory.RecordsInstallation(target) {
+		target.PrivateBucket = true
+	}
+
+	if target.ClusterName == "" || target.Region == "" {
+		return nil, nil, fmt.Errorf("could not find the cluster name and region in %s, pass the cluster name and --region", cleanupWorkspace)
+	}
+	return target, inventory, nil
+}
+
+// printCleanupPlan lists what cleanup would remove
+func printCleanupPlan(installation *util.Installation, plan *util.CleanupPlan) {
+	fmt.Printf("Resources of cluster '%s' in region '%s' that cleanup would remove:\n", installation.ClusterName, installation.Region)
+	if plan.Empty() {
+		fmt.Println("  none found")
+		return
+	}
+	if plan.DestroyDir != "" {
+		target := plan.DestroyDir
+		if plan.InfraID != "" {
+			target = fmt.Sprintf("%s (infraID %s)", plan.DestroyDir, plan.InfraID)
+		}
+		fmt.Printf("  Infrastructure (openshift-install destroy): %s\n", target)
+	}
+	for _, role := range plan.IAMRoles {
+		fmt.Printf("  IAM role: %s\n", role)
+	}
+	if plan.OIDCProvider != "" {
+		fmt.Printf("  OIDC provider: %s\n", plan.OIDCProvider)
+	}
+	if plan.S3Bucket != "" {
+		fmt.Printf("  S3 bucket: %s\n", plan.S3Bucket)
+	}
+	if plan.CloudFrontDistribution != "" {
+		fmt.Printf("  CloudFront distribution: %s\n", plan.CloudFrontDistribution)
+	}
 }
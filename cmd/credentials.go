@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/errors"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// validateAWSCredentials checks the credentials of the profile and reports
+// who they belong to and when they expire
+func validateAWSCredentials(log *logger.Logger, profile string) (*util.CallerIdentity, error) {
+	identity, err := util.ValidateAWSCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("✓ AWS credentials are valid")
+	log.Info(fmt.Sprintf("  Account: %s", identity.Account))
+	log.Info(fmt.Sprintf("  ARN:     %s", identity.ARN))
+	switch {
+	case identity.ExpiryUnknown:
+		log.Info("  Expires: unknown (session token)")
+	case identity.Expiration.IsZero():
+		log.Info("  Expires: never (long-term access keys)")
+	default:
+		log.Info(fmt.Sprintf("  Expires: %s (in %s)", identity.Expiration.Local().Format(time.RFC1123),
+			time.Until(identity.Expiration).Round(time.Minute)))
+	}
+	return identity, nil
+}
+
+// validateAWSProfiles validates the credentials of every profile the
+// installation uses and returns the identities by profile
+func validateAWSProfiles(log *logger.Logger, cfg *config.Config) (map[string]*util.CallerIdentity, error) {
+	identities := map[string]*util.CallerIdentity{}
+	for _, profile := range cfg.Profiles() {
+		log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", profile))
+		identity, err := validateAWSCredentials(log, profile)
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s': %w", profile, err)
+		}
+		identities[profile] = identity
+	}
+	return identities, nil
+}
+
+// checkHostedZoneRole warns when Step 5, which writes the role of the DNS
+// profile to install-config.yaml, ran before awsProfiles.dns was set
+func checkHostedZoneRole(log *logger.Logger, cfg *config.Config, versionArch string, summary *errors.Summary) {
+	if cfg.AwsProfiles.DNS == "" {
+		return
+	}
+	installConfig, err := util.LoadInstallConfig(versionArch)
+	if err != nil {
+		return
+	}
+	roleARN, err := util.AWSProfileRoleARN(cfg.AwsProfiles.DNS)
+	if err == nil && installConfig.Platform.AWS.HostedZoneRole == roleARN {
+		return
+	}
+	message := fmt.Sprintf("install-config.yaml has no platform.aws.hostedZoneRole for the dns profile '%s', Step 5 ran before it was set; "+
+		"rerun with --start-from-step=5 before Step 6 consumes install-config.yaml", cfg.AwsProfiles.DNS)
+	log.Info(fmt.Sprintf("⚠  Warning: %s", message))
+	summary.AddDetail("AWS profiles", message)
+}
+
+// awsSteps are the steps whose commands call AWS with the profile credentials
+var awsSteps = map[int]bool{4: true, 7: true, 10: true}
+
+// stepProfile returns the profile of the AWS calls of a step: the IAM one
+// for Step 7, the infra one otherwise
+func stepProfile(cfg *config.Config, stepNum int) string {
+	if stepNum == 7 {
+		return cfg.IAMProfile()
+	}
+	return cfg.InfraProfile()
+}
+
+// profileEnv returns the proxy environment plus the credentials of a profile
+func profileEnv(cfg *config.Config, profile string) ([]string, error) {
+	awsEnv, err := util.NewAWSCredentialProvider(profile).Env()
+	if err != nil {
+		return nil, err
+	}
+	return append(cfg.Proxy.EnvVars(), awsEnv...), nil
+}
+
+// checkAWSCredentials reads the profile of a step again before it calls AWS,
+// so credentials refreshed outside the installer are picked up. Before Step
+// 10, which runs for 40 minutes or more, the credentials must also outlive
+// the configured minimum lifetime; credentials that can be obtained again
+// (assumed roles, SSO, credential_process) are refreshed first. It returns
+// an error when the installation must stop.
+func checkAWSCredentials(log *logger.Logger, cfg *config.Config, stepNum int, summary *errors.Summary) error {
+	profile := stepProfile(cfg, stepNum)
+	var minLifetime time.Duration
+	if stepNum == 10 {
+		minLifetime = cfg.CredentialExpiry.Threshold()
+	}
+
+	creds, err := util.ResolveAWSCredentials(profile)
+	if err == nil && creds.ExpiresWithin(minLifetime, time.Now()) && refreshableCredentials(creds) {
+		log.Debug(fmt.Sprintf("Refreshing %s credentials of profile '%s', they expire at %s",
+			creds.Source, profile, creds.Expiration.Local().Format(time.RFC1123)))
+		creds, err = util.RefreshAWSCredentials(profile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read AWS credentials from profile '%s': %w", profile, err)
+	}
+	if creds.Expiration.IsZero() {
+		log.Debug(fmt.Sprintf("Using %s credentials of profile '%s'", creds.Source, profile))
+	} else {
+		log.Debug(fmt.Sprintf("Using %s credentials of profile '%s', expiring at %s",
+			creds.Source, profile, creds.Expiration.Local().Format(time.RFC1123)))
+	}
+
+	now := time.Now()
+	if creds.ExpiresWithin(0, now) {
+		return fmt.Errorf("AWS credentials of profile '%s' expired at %s, refresh them and run install again",
+			profile, creds.Expiration.Local().Format(time.RFC1123))
+	}
+	if stepNum != 10 {
+		return nil
+	}
+
+	if creds.ExpiryUnknown() {
+		message := fmt.Sprintf("profile '%s' uses a session token of unknown expiry, it may expire during the deployment", profile)
+		log.Info(fmt.Sprintf("⚠  Warning: %s", message))
+		summary.AddDetail("Credential expiry", message)
+		return nil
+	}
+	if !creds.ExpiresWithin(minLifetime, now) {
+		return nil
+	}
+
+	message := fmt.Sprintf("AWS credentials of profile '%s' expire in %s, less than the %s the deployment may take (credentialExpiry.minLifetime)",
+		profile, creds.Expiration.Sub(now).Round(time.Minute), minLifetime)
+	if cfg.CredentialExpiry.Action == config.CredentialExpiryWarn {
+		log.Info(fmt.Sprintf("⚠  Warning: %s", message))
+		summary.AddDetail("Credential expiry", message)
+		return nil
+	}
+	return fmt.Errorf("%s; refresh them and run install again, or set credentialExpiry.action to %s", message, config.CredentialExpiryWarn)
+}
+
+// refreshableCredentials reports whether resolving the profile again returns
+// new credentials
+func refreshableCredentials(creds *util.AWSCredentials) bool {
+	switch creds.Source {
+	case util.CredentialSourceAssumeRole, util.CredentialSourceSSO, util.CredentialSourceProcess:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/errors"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+)
+
+func TestCheckAWSCredentialsFreshAssumedRole(t *testing.T) {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, ".aws"), 0755)
+	t.Setenv("HOME", home)
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE", "AWS_ENDPOINT_URL"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = base-secret
+`), 0600)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`[profile installer]
+role_arn = arn:aws:iam::123456789012:role/installer
+source_profile = base
+region = us-east-2
+`), 0600)
+
+	// AssumeRole returns credentials of the default one-hour session
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+			<AccessKeyId>ASIAINSTALLER</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>
+			<SessionToken>token</SessionToken><Expiration>%s</Expiration>
+		</Credentials></AssumeRoleResult></AssumeRoleResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	cfg := &config.Config{AwsProfile: "installer"}
+	cfg.SetDefaults()
+	summary := errors.NewSummary()
+	if err := checkAWSCredentials(logger.New(logger.LevelQuiet, nil), cfg, 10, summary); err != nil {
+		t.Errorf("Expected one-hour credentials to pass the Step 10 check, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/iac"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/steps"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+var (
+	exportFormat       string
+	exportReleaseImage string
+	exportClusterName  string
+	exportAwsRegion    string
+	exportOutputDir    string
+)
+
+var exportIACCmd = &cobra.Command{
+	Use:   "export-iac",
+	Short: "Export the IAM and OIDC resources as CloudFormation or Terraform",
+	Long: `Converts the output of ccoctl --dry-run for the current credentials requests
+into a template with the OIDC provider, S3 bucket (and CloudFront), IAM roles
+and inline policies. Cluster name and region are template parameters.`,
+	Run: runExportIAC,
+}
+
+func init() {
+	rootCmd.AddCommand(exportIACCmd)
+
+	exportIACCmd.Flags().StringVar(&exportFormat, "format", "", "Template format: cloudformation or terraform")
+	exportIACCmd.Flags().StringVar(&exportReleaseImage, "release-image", "", "OpenShift release image (to find correct version directory)")
+	exportIACCmd.Flags().StringVar(&exportClusterName, "cluster-name", "", "Cluster name (default: from install-config.yaml)")
+	exportIACCmd.Flags().StringVar(&exportAwsRegion, "region", "", "AWS region (default: from install-config.yaml)")
+	exportIACCmd.Flags().StringVar(&exportOutputDir, "output-dir", "iac", "Directory for the template and the signing key")
+	exportIACCmd.MarkFlagRequired("format")
+}
+
+func runExportIAC(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if exportFormat != iac.FormatCloudFormation && exportFormat != iac.FormatTerraform {
+		log.Error(fmt.Sprintf("Unknown format %q (expected %s or %s)", exportFormat, iac.FormatCloudFormation, iac.FormatTerraform))
+		os.Exit(1)
+	}
+
+	cfg := loadConfig(log)
+	if exportReleaseImage != "" {
+		cfg.ReleaseImage = exportReleaseImage
+	}
+	if exportClusterName != "" {
+		cfg.ClusterName = exportClusterName
+	}
+	if exportAwsRegion != "" {
+		cfg.AwsRegion = exportAwsRegion
+	}
+
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		log.Error(fmt.Sprintf("A release image is required: %v", err))
+		os.Exit(1)
+	}
+	if cfg.ClusterName == "" || cfg.AwsRegion == "" {
+		// Step 6 consumes install-config.yaml, the backup from Step 5 remains
+		installConfigPath := util.GetInstallConfigPath(versionArch)
+		for _, path := range []string{installConfigPath, installConfigPath + ".backup"} {
+			name, region, err := util.ExtractClusterNameAndRegion(path)
+			if err != nil {
+				continue
+			}
+			if cfg.ClusterName == "" {
+				cfg.ClusterName = name
+			}
+			if cfg.AwsRegion == "" {
+				cfg.AwsRegion = region
+			}
+			break
+		}
+	}
+	if !util.DirExistsWithFiles(util.GetCredReqsPath(versionArch)) {
+		log.Error(fmt.Sprintf("No credentials requests in %s, run install up to Step 3 first", util.GetCredReqsPath(versionArch)))
+		os.Exit(1)
+	}
+
+	log.StartStep("Running ccoctl dry run")
+	dryRunDir, err := steps.CcoctlDryRun(cfg, log, &util.RealExecutor{})
+	if err != nil {
+		log.FailStep("ccoctl dry run")
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	log.CompleteStep("ccoctl dry run")
+
+	// The dry run holds a signing private key, it must go before any exit
+	err = writeIACExport(log, cfg, dryRunDir)
+	if removeErr := os.RemoveAll(dryRunDir); removeErr != nil {
+		log.Error(fmt.Sprintf("Failed to remove the ccoctl dry run in %s: %v", dryRunDir, removeErr))
+	}
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// writeIACExport renders the template from the ccoctl dry run, and writes it
+// with the discovery documents and the signing key to the output directory
+func writeIACExport(log *logger.Logger, cfg *config.Config, dryRunDir string) error {
+	output, err := util.ParseDryRunOutput(dryRunDir)
+	if err != nil {
+		return err
+	}
+
+	template, err := iac.Render(exportFormat, output, iac.Options{
+		ClusterName:   cfg.ClusterName,
+		Region:        cfg.AwsRegion,
+		PrivateBucket: cfg.PrivateBucket,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := util.EnsureDir(exportOutputDir); err != nil {
+		return err
+	}
+	templateFile := "oidc-iam.cfn.json"
+	if exportFormat == iac.FormatTerraform {
+		templateFile = "oidc-iam.tf"
+	}
+	templatePath := filepath.Join(exportOutputDir, templateFile)
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Template written to %s", templatePath))
+
+	// CloudFormation cannot upload objects, so the discovery documents are written next to the template
+	if exportFormat == iac.FormatCloudFormation {
+		discoveryDir := filepath.Join(exportOutputDir, "discovery")
+		for key, content := range output.Discovery {
+			path := filepath.Join(discoveryDir, key)
+			if err := util.EnsureDir(filepath.Dir(path)); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return err
+			}
+		}
+		log.Info(fmt.Sprintf("Upload the OIDC discovery documents after creating the stack: aws s3 cp --recursive %s s3://<bucket>/", discoveryDir))
+	}
+
+	// The published keys match this key; it is needed to install with the exported resources
+	if output.SigningKey != "" {
+		key, err := os.ReadFile(output.SigningKey)
+		if err != nil {
+			return err
+		}
+		// Recreated, so that it is never readable by others, even briefly
+		keyPath := filepath.Join(exportOutputDir, "bound-service-account-signing-key.key")
+		if err := os.Remove(keyPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			return err
+		}
+		log.Info(fmt.Sprintf("Service account signing key written to %s, keep it safe and use it as byoIAM.signingKey", keyPath))
+	}
+	return nil
+}
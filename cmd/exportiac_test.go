@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/iac"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+)
+
+func TestWriteIACExport(t *testing.T) {
+	dryRunDir := t.TempDir()
+	files := map[string]string{
+		"01-bucket.json":                `{"Bucket":"test-cluster-oidc"}`,
+		"02-openid-configuration":       `{"issuer":"https://test-cluster-oidc.s3.us-east-2.amazonaws.com"}`,
+		"03-iam-identity-provider":      `{"Url":"https://test-cluster-oidc.s3.us-east-2.amazonaws.com","ClientIDList":["openshift","sts.amazonaws.com"],"ThumbprintList":["abc"]}`,
+		"04-iam-role-ingress":           `{"RoleName":"test-cluster-openshift-ingress","Description":"ingress role","AssumeRolePolicyDocument":"{\"Version\":\"2012-10-17\"}"}`,
+		"05-iam-role-policy-ingress":    `{"RoleName":"test-cluster-openshift-ingress","PolicyName":"test-cluster-openshift-ingress","PolicyDocument":"{\"Statement\":[]}"}`,
+		"serviceaccount-signer.private": "private key",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dryRunDir, name), []byte(content), 0644)
+	}
+
+	defer func(format, outputDir string) { exportFormat, exportOutputDir = format, outputDir }(exportFormat, exportOutputDir)
+	exportFormat = iac.FormatTerraform
+	exportOutputDir = t.TempDir()
+	// A key left by an earlier export with looser permissions
+	keyPath := filepath.Join(exportOutputDir, "bound-service-account-signing-key.key")
+	os.WriteFile(keyPath, []byte("old key"), 0644)
+
+	cfg := &config.Config{ClusterName: "test-cluster", AwsRegion: "us-east-2"}
+	if err := writeIACExport(logger.New(logger.LevelQuiet, nil), cfg, dryRunDir); err != nil {
+		t.Fatalf("writeIACExport failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(exportOutputDir, "oidc-iam.tf")); err != nil {
+		t.Errorf("Expected the template to be written: %v", err)
+	}
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		t.Fatalf("Expected the signing key to be written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the signing key to be readable by its owner only, got %v", info.Mode().Perm())
+	}
+	if content, _ := os.ReadFile(keyPath); string(content) != "private key" {
+		t.Errorf("Unexpected signing key %q", content)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/errors"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// guardrailEnforcer checks the configured guardrails, letting through the
+// ones overridden on the command line
+type guardrailEnforcer struct {
+	guardrails config.GuardrailsConfig
+	overrides  []string
+	// recorded avoids reporting an overridden violation once per check
+	recorded map[string]bool
+}
+
+func newGuardrailEnforcer(cfg *config.Config, overrides []string) *guardrailEnforcer {
+	return &guardrailEnforcer{guardrails: cfg.Guardrails, overrides: overrides, recorded: map[string]bool{}}
+}
+
+// enforce checks the target. Violations of overridden guardrails are logged
+// and recorded in the summary, any other violation is returned as an error.
+func (g *guardrailEnforcer) enforce(log *logger.Logger, target config.GuardrailTarget, summary *errors.Summary) error {
+	var violations []string
+	for _, violation := range g.guardrails.Check(target) {
+		if !g.overridden(violation.Guardrail) {
+			violations = append(violations, violation.Error())
+			continue
+		}
+		if !g.recorded[violation.Error()] {
+			g.recorded[violation.Error()] = true
+			log.Info(fmt.Sprintf("⚠  Overriding %s", violation.Error()))
+			summary.AddDetail("Guardrail overrides", fmt.Sprintf("%s (--override-guardrail=%s)", violation.Error(), violation.Guardrail))
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%s; if this is intended, rerun with --override-guardrail=<name>", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// enforceInstallation checks the cluster described by the configuration or
+// install-config.yaml, built with the infra profile, and the account of the
+// IAM profile
+func (g *guardrailEnforcer) enforceInstallation(log *logger.Logger, cfg *config.Config, identities map[string]*util.CallerIdentity,
+	versionArch string, summary *errors.Summary) error {
+	if err := g.enforce(log, guardrailTarget(cfg, identities[cfg.InfraProfile()].Account, versionArch), summary); err != nil {
+		return err
+	}
+	return g.enforce(log, config.GuardrailTarget{Account: identities[cfg.IAMProfile()].Account}, summary)
+}
+
+func (g *guardrailEnforcer) overridden(name string) bool {
+	for _, override := range g.overrides {
+		if override == name {
+			return true
+		}
+	}
+	return false
+}
+
+// guardrailTarget describes the cluster to install. It is read from
+// install-config.yaml, or the backup taken before Step 6 consumed it, once
+// that exists, and from the configuration before.
+func guardrailTarget(cfg *config.Config, account, versionArch string) config.GuardrailTarget {
+	target := config.GuardrailTarget{
+		Account:      account,
+		Region:       cfg.AwsRegion,
+		ClusterName:  cfg.ClusterName,
+		MachinePools: []config.GuardrailPool{{Name: "default", InstanceType: cfg.InstanceType}},
+	}
+
+	installConfig, err := util.LoadInstallConfig(versionArch)
+	if err != nil {
+		// Not created yet
+		return target
+	}
+	if installConfig.Metadata.Name != "" {
+		target.ClusterName = installConfig.Metadata.Name
+	}
+	if installConfig.Platform.AWS.Region != "" {
+		target.Region = installConfig.Platform.AWS.Region
+	}
+	target.MachinePools = nil
+	for _, pool := range installConfig.MachinePools() {
+		instanceType := pool.Platform.AWS.Type
+		if instanceType == "" {
+			// Step 5 fills in the configured instance type
+			instanceType = cfg.InstanceType
+		}
+		target.MachinePools = append(target.MachinePools, config.GuardrailPool{
+			Name:         pool.Name,
+			Replicas:     pool.Replicas,
+			InstanceType: instanceType,
+		})
+	}
+	return target
+}
@@ -16,13 +16,21 @@ import (
 )
 
 var (
-	releaseImage    string
-	awsProfile      string
-	pullSecretPath  string
-	privateBucket   bool
-	startFromStep   int
-	confirmEachStep bool
-	instanceType    string
+	releaseImage     string
+	awsProfile       string
+	pullSecretPath   string
+	privateBucket    bool
+	startFromStep    int
+	confirmEachStep  bool
+	instanceType     string
+	hardening        string
+	kmsKeyARN        string
+	reviewManifests  bool
+	stopForIAMReview bool
+	approveIAM       string
+	skipPreflight    bool
+	// overrideGuardrails are the guardrails whose violations are accepted
+	overrideGuardrails []string
 )
 
 var installCmd = &cobra.Command{
@@ -42,8 +50,19 @@ func init() {
 	installCmd.Flags().IntVar(&startFromStep, "start-from-step", 0, "Start from specific step number")
 	installCmd.Flags().BoolVar(&confirmEachStep, "confirm-each-step", false, "Prompt for confirmation before executing each step")
 	installCmd.Flags().StringVar(&instanceType, "instance-type", "m5.4xlarge", "AWS instance type for controlPlane and compute pools")
+	installCmd.Flags().StringVar(&hardening, "hardening", "", "Security hardening profile: baseline or strict")
+	installCmd.Flags().StringVar(&kmsKeyARN, "kms-key-arn", "", "Customer-managed KMS key for EBS root volumes (required by --hardening=strict)")
+	installCmd.Flags().BoolVar(&reviewManifests, "review-manifests", false, "Review the manifests and record the decision before deploying the cluster")
+	installCmd.Flags().BoolVar(&stopForIAMReview, "stop-for-iam-review", false, "Collect the IAM resources of Step 7 for review and pause before creating them")
+	installCmd.Flags().StringVar(&approveIAM, "approve-iam", "", "Continue after an IAM review, given the review hash")
+	installCmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the IAM permission simulation before Step 1 and the AWS preflight checks before Step 7")
+	installCmd.Flags().StringSliceVar(&overrideGuardrails, "override-guardrail", nil,
+		fmt.Sprintf("Accept violations of a guardrail, repeatable (%s)", strings.Join(config.Guardrails, ", ")))
 }
 
+// exitPaused is the exit code of an installation paused for a review
+const exitPaused = 3
+
 func runInstall(cmd *cobra.Command, args []string) {
 	// Create logger
 	log := logger.New(logger.Level(getLogLevel()), nil)
@@ -62,14 +81,22 @@ func runInstall(cmd *cobra.Command, args []string) {
 		log.Error(fmt.Sprintf("Configuration error: %v", err))
 		os.Exit(1)
 	}
+	if err := config.ValidateGuardrailOverrides(overrideGuardrails); err != nil {
+		log.Error(fmt.Sprintf("Configuration error: %v", err))
+		os.Exit(1)
+	}
+
+	if err := config.CheckAWSCLI(config.AWSCLIFeatures(cfg)...); err != nil {
+		log.Error(fmt.Sprintf("Prerequisite check failed: %v", err))
+		os.Exit(1)
+	}
 
 	// Validate AWS credentials
-	log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", cfg.AwsProfile))
-	if err := util.ValidateAWSCredentials(cfg.AwsProfile); err != nil {
+	identities, err := validateAWSProfiles(log, cfg)
+	if err != nil {
 		log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
 		os.Exit(1)
 	}
-	log.Info("✓ AWS credentials are valid")
 
 	// Set OutputDir to be under the version-specific artifacts directory
 	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
@@ -94,15 +121,65 @@ func runInstall(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// Validate the proxy trust bundle
+	if cfg.TrustBundle != "" {
+		if err := config.ValidateTrustBundle(cfg.TrustBundle); err != nil {
+			log.Error(fmt.Sprintf("Trust bundle validation failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// Validate the reused service-account signing key pair
+	if cfg.SigningKey != nil {
+		if err := config.ValidateSigningKeyPair(cfg.SigningKey.PrivateKey, cfg.SigningKey.PublicKey); err != nil {
+			log.Error(fmt.Sprintf("Signing key validation failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
 	// Create command executor
 	executor := &util.RealExecutor{}
 
 	// Create step detector
 	detector := steps.NewDetector(cfg)
 
+	// The IAM review happens right before Step 7, it cannot apply when Step 7 does not run
+	if (approveIAM != "" || stopForIAMReview) && detector.ShouldSkipStep(7) {
+		log.Error("--approve-iam and --stop-for-iam-review only apply when Step 7 runs, and Step 7 is completed or skipped")
+		os.Exit(1)
+	}
+
 	// Create error summary
 	summary := errors.NewSummary()
 
+	// Check the account, region and cluster shape against the guardrails
+	guardrails := newGuardrailEnforcer(cfg, overrideGuardrails)
+	if err := guardrails.enforceInstallation(log, cfg, identities, versionArch, summary); err != nil {
+		log.Error(fmt.Sprintf("Guardrail check failed: %v", err))
+		os.Exit(1)
+	}
+
+	// Simulate the IAM policies of the installing identity before Step 1
+	if !cfg.SkipPreflight {
+		if err := checkPermissions(log, cfg, executor, detector, identities, versionArch, summary); err != nil {
+			log.Error(fmt.Sprintf("IAM permission check failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// Resolve the hardening profile against what the release supports
+	hardeningPlan, err := steps.HardeningPlan(cfg)
+	if err != nil {
+		log.Error(fmt.Sprintf("Hardening check failed: %v", err))
+		os.Exit(1)
+	}
+	// Each step reports the settings it applied
+	for _, setting := range hardeningPlan {
+		if setting.Name == steps.HardeningPrivateOIDCBucket {
+			cfg.PrivateBucket = true
+		}
+	}
+
 	// Execute all steps
 	allSteps := []struct {
 		num     int
@@ -143,6 +220,8 @@ func runInstall(cmd *cobra.Command, args []string) {
 		}},
 	}
 
+	// Whether the guardrails were checked against install-config.yaml
+	installConfigChecked := false
 	for _, stepDef := range allSteps {
 		// Create step to get its name
 		step, err := stepDef.factory(cfg, log, executor)
@@ -154,6 +233,9 @@ func runInstall(cmd *cobra.Command, args []string) {
 
 		if detector.ShouldSkipStep(stepDef.num) {
 			log.Info(fmt.Sprintf("⏭  Skipping [Step %d] %s (already completed)", stepDef.num, step.Name()))
+			if stepDef.num == 5 {
+				checkHostedZoneRole(log, cfg, versionArch, summary)
+			}
 			continue
 		}
 
@@ -161,13 +243,83 @@ func runInstall(cmd *cobra.Command, args []string) {
 		if cfg.ConfirmEachStep {
 			if !confirm(fmt.Sprintf("Proceed with [Step %d] %s? [y/N] ", stepDef.num, step.Name())) {
 				log.Info(fmt.Sprintf("⏭  Skipping [Step %d] %s (user choice)", stepDef.num, step.Name()))
+				if stepDef.num == 7 && (approveIAM != "" || stopForIAMReview) {
+					summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()),
+						fmt.Errorf("the IAM review flags only apply when Step 7 runs"))
+					break
+				}
 				continue
 			}
 		}
 
+		// Check the guardrails again on the final install-config.yaml before
+		// Step 6 consumes it, or before the first step creating AWS resources
+		// when Step 6 did not run
+		if stepDef.num == 6 || (!installConfigChecked && (stepDef.num == 7 || stepDef.num == 10)) {
+			if err := guardrails.enforceInstallation(log, cfg, identities, versionArch, summary); err != nil {
+				summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
+				break
+			}
+			installConfigChecked = true
+		}
+
+		// Preflight checks before Step 7 creates the first AWS resources
+		if stepDef.num == 7 && !cfg.SkipPreflight {
+			if err := runPreflight(log, cfg, executor, versionArch, summary); err != nil {
+				summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
+				break
+			}
+		}
+
+		// IAM review gate before Step 7 creates the IAM resources
+		if stepDef.num == 7 {
+			pause, err := iamReviewGate(cfg, log, executor, summary)
+			if err != nil {
+				summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
+				break
+			}
+			if pause {
+				summary.Pause(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
+				break
+			}
+		}
+
+		// Review gate for the manifests going into the cluster
+		if stepDef.num == 10 && cfg.ReviewManifests {
+			gate := &steps.ReviewGate{
+				VersionDir: filepath.Join("artifacts", versionArch),
+				OutputDir:  cfg.OutputDir,
+				In:         os.Stdin,
+				Out:        os.Stdout,
+			}
+			approved, err := gate.Run()
+			if err == nil && !approved {
+				err = fmt.Errorf("manifests were rejected by the reviewer")
+			}
+			if err != nil {
+				summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
+				break
+			}
+			summary.AddDetail("Manifest review", fmt.Sprintf("approved, recorded in %s", filepath.Join("artifacts", versionArch, steps.ReviewLogFile)))
+		}
+
+		// Pick up refreshed credentials, and make sure they outlive Step 10
+		if awsSteps[stepDef.num] {
+			if err := checkAWSCredentials(log, cfg, stepDef.num, summary); err != nil {
+				summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
+				break
+			}
+		}
+
 		log.StartStep(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
 
-		if err := step.Execute(); err != nil {
+		err = step.Execute()
+		if reporter, ok := step.(steps.Reporter); ok {
+			for _, detail := range reporter.Details() {
+				summary.AddDetail(detail.Title, detail.Line)
+			}
+		}
+		if err != nil {
 			log.FailStep(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
 			summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
 			break
@@ -223,6 +375,46 @@ func runInstall(cmd *cobra.Command, args []string) {
 	if summary.HasErrors() {
 		os.Exit(1)
 	}
+	if summary.IsPaused() {
+		os.Exit(exitPaused)
+	}
+}
+
+// iamReviewGate prepares or verifies the IAM review requested on the command
+// line. It returns true when the installation must pause for the review.
+func iamReviewGate(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, summary *errors.Summary) (bool, error) {
+	review, err := steps.NewIAMReview(cfg, log, executor)
+	if err != nil {
+		return false, err
+	}
+
+	if approveIAM != "" {
+		if err := review.VerifyApproval(approveIAM); err != nil {
+			return false, err
+		}
+		summary.AddDetail("IAM review", fmt.Sprintf("approved with hash %s", approveIAM))
+		return false, nil
+	}
+
+	if stopForIAMReview {
+		if cfg.BYOIAM != nil {
+			return false, fmt.Errorf("--stop-for-iam-review does not apply to byoIAM, the IAM roles are not created by the installer")
+		}
+		log.Info("Collecting IAM resources for review (ccoctl dry run)...")
+		hash, err := review.Prepare()
+		if err != nil {
+			return false, err
+		}
+		summary.AddDetail("IAM review", fmt.Sprintf("resources and summary written to %s", review.Dir()))
+		summary.AddDetail("IAM review", fmt.Sprintf("review hash %s", hash))
+		summary.AddDetail("IAM review", fmt.Sprintf("continue with: install --approve-iam=%s", hash))
+		return true, nil
+	}
+
+	if review.Pending() {
+		return false, fmt.Errorf("an IAM review is pending, continue with --approve-iam=<hash> or run --stop-for-iam-review again")
+	}
+	return false, nil
 }
 
 func loadConfig(log *logger.Logger) *config.Config {
@@ -255,6 +447,10 @@ func loadConfig(log *logger.Logger) *config.Config {
 		StartFromStep:   startFromStep,
 		ConfirmEachStep: confirmEachStep,
 		InstanceType:    instanceType,
+		Hardening:       hardening,
+		KMSKeyARN:       kmsKeyARN,
+		ReviewManifests: reviewManifests,
+		SkipPreflight:   skipPreflight,
 	}
 	cfg.Merge(flagCfg)
 
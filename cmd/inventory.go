@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+var inventoryJSON bool
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Show the cloud resources created by the installation",
+	Long:  `Prints the IAM roles, OIDC provider, S3 bucket, CloudFront distribution and infraID recorded in inventory.json`,
+	Run:   runInventory,
+}
+
+func init() {
+	rootCmd.AddCommand(inventoryCmd)
+
+	inventoryCmd.Flags().BoolVar(&inventoryJSON, "json", false, "Print the inventory as JSON")
+}
+
+func runInventory(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if !util.FileExists(util.InventoryFile) {
+		log.Error(fmt.Sprintf("No %s in this workspace, no resources have been recorded", util.InventoryFile))
+		os.Exit(1)
+	}
+	inventory, err := util.LoadInventory(util.InventoryFile)
+	checkErr(err)
+
+	if inventoryJSON {
+		out, err := json.MarshalIndent(inventory, "", "  ")
+		checkErr(err)
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Printf("Cluster:        %s\n", inventory.ClusterName)
+	fmt.Printf("Region:         %s\n", inventory.Region)
+	if inventory.ReleaseImage != "" {
+		fmt.Printf("Release image:  %s\n", inventory.ReleaseImage)
+	}
+	if inventory.InfraID != "" {
+		fmt.Printf("Infra ID:       %s\n", inventory.InfraID)
+	}
+	if inventory.ClusterID != "" {
+		fmt.Printf("Cluster ID:     %s\n", inventory.ClusterID)
+	}
+	if inventory.OIDCProviderARN != "" {
+		fmt.Printf("OIDC provider:  %s\n", inventory.OIDCProviderARN)
+	}
+	if inventory.S3Bucket != "" {
+		fmt.Printf("S3 bucket:      %s\n", inventory.S3Bucket)
+	}
+	if inventory.CloudFrontDistribution != "" {
+		fmt.Printf("CloudFront:     %s\n", inventory.CloudFrontDistribution)
+	}
+	fmt.Printf("IAM roles (%d):\n", len(inventory.IAMRoles))
+	for _, role := range inventory.IAMRoles {
+		fmt.Printf("  - %s\n", role)
+	}
+	fmt.Printf("Updated:        %s\n", inventory.UpdatedAt.Format("2006-01-02 15:04:05 MST"))
+}
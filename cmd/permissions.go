@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/errors"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/steps"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// checkPermissions simulates the IAM policies of the identity of each step,
+// the IAM or the infra profile, against the actions the remaining steps need,
+// and reports the denied ones by step. A simulation that cannot run is only
+// a warning.
+func checkPermissions(log *logger.Logger, cfg *config.Config, executor util.CommandExecutor, detector *steps.Detector,
+	identities map[string]*util.CallerIdentity, versionArch string, summary *errors.Summary) error {
+	warn := func(err error) error {
+		message := fmt.Sprintf("IAM permissions were not checked: %v", err)
+		log.Info(fmt.Sprintf("⚠  Warning: %s", message))
+		summary.AddDetail("IAM permissions", message)
+		return nil
+	}
+
+	// The principal and environment of each profile
+	principals := map[string]string{}
+	envs := map[string][]string{}
+	for _, profile := range []string{cfg.InfraProfile(), cfg.IAMProfile()} {
+		if _, ok := principals[profile]; ok {
+			continue
+		}
+		env, err := profileEnv(cfg, profile)
+		if err != nil {
+			return err
+		}
+		principal, err := util.PolicySourceARN(executor, env, identities[profile].ARN)
+		if err != nil {
+			return warn(err)
+		}
+		log.Info(fmt.Sprintf("Simulating the IAM policies of %s...", principal))
+		principals[profile], envs[profile] = principal, env
+	}
+
+	installConfig, _ := util.LoadInstallConfig(versionArch)
+	denied := map[int][]string{}
+	var deniedSteps []int
+	for _, set := range steps.RequiredPermissions(cfg, installConfig) {
+		if detector.ShouldSkipStep(set.Step) {
+			continue
+		}
+		profile := stepProfile(cfg, set.Step)
+		actions, err := util.SimulatePrincipalPolicy(executor, envs[profile], principals[profile], set.Actions)
+		if err != nil {
+			return warn(err)
+		}
+		for _, action := range actions {
+			if len(denied[set.Step]) == 0 {
+				deniedSteps = append(deniedSteps, set.Step)
+			}
+			denied[set.Step] = append(denied[set.Step], fmt.Sprintf("%s (%s)", action, set.Description))
+		}
+	}
+	if len(deniedSteps) == 0 {
+		log.Info("✓ IAM permissions allow every step")
+		return nil
+	}
+
+	for _, step := range deniedSteps {
+		for _, action := range denied[step] {
+			log.Error(fmt.Sprintf("[Step %d] denied: %s", step, action))
+			summary.AddDetail(fmt.Sprintf("Denied IAM actions of Step %d", step), action)
+		}
+	}
+	var counts []string
+	for _, step := range deniedSteps {
+		counts = append(counts, fmt.Sprintf("%d for Step %d (%s)", len(denied[step]), step, principals[stepProfile(cfg, step)]))
+	}
+	return fmt.Errorf("missing IAM permissions: %s denied; see print-required-policy for the policy to grant, or rerun with --skip-preflight",
+		strings.Join(counts, ", "))
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/errors"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/steps"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// runPreflight checks the AWS prerequisites of Steps 7 and 10 against
+// install-config.yaml and reports every missing one in the summary
+func runPreflight(log *logger.Logger, cfg *config.Config, executor util.CommandExecutor, versionArch string, summary *errors.Summary) error {
+	installConfig, err := util.LoadInstallConfig(versionArch)
+	if err != nil {
+		return fmt.Errorf("preflight checks need install-config.yaml: %w", err)
+	}
+	region := installConfig.Platform.AWS.Region
+	if region == "" {
+		region = cfg.AwsRegion
+	}
+	clusterName := installConfig.Metadata.Name
+	if clusterName == "" {
+		clusterName = cfg.ClusterName
+	}
+
+	env, err := profileEnv(cfg, cfg.InfraProfile())
+	if err != nil {
+		return err
+	}
+	iamEnv, err := profileEnv(cfg, cfg.IAMProfile())
+	if err != nil {
+		return err
+	}
+	var dnsEnv []string
+	if cfg.AwsProfiles.DNS != "" {
+		if dnsEnv, err = profileEnv(cfg, cfg.AwsProfiles.DNS); err != nil {
+			return err
+		}
+	}
+
+	log.Info(fmt.Sprintf("Running AWS preflight checks in %s...", region))
+	findings, err := util.RunPreflight(executor, env, util.PreflightInput{
+		ClusterName:         clusterName,
+		Region:              region,
+		InstallConfig:       installConfig,
+		DefaultInstanceType: cfg.InstanceType,
+		WithOIDC:            cfg.BYOIAM == nil,
+		PrivateBucket:       cfg.PrivateBucket,
+		OwnProviderARN:      steps.CcoctlIdentityProvider(cfg.OutputDir),
+		IAMEnv:              iamEnv,
+		DNSEnv:              dnsEnv,
+	})
+	if err != nil {
+		return fmt.Errorf("preflight checks could not run: %w (use --skip-preflight to skip them)", err)
+	}
+	if len(findings) == 0 {
+		log.Info("✓ AWS preflight checks passed")
+		return nil
+	}
+	for _, finding := range findings {
+		log.Error(fmt.Sprintf("Preflight %s: %s", finding.Check, finding.Message))
+		summary.AddDetail("Preflight", fmt.Sprintf("%s: %s", finding.Check, finding.Message))
+	}
+	return fmt.Errorf("%d preflight check(s) failed, fix them or rerun with --skip-preflight", len(findings))
+}
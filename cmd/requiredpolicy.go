@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/steps"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// managedPolicySizeLimit is the largest IAM managed policy, whitespace excluded
+const managedPolicySizeLimit = 6144
+
+var (
+	policyReleaseImage string
+	policyOutput       string
+)
+
+var printRequiredPolicyCmd = &cobra.Command{
+	Use:   "print-required-policy",
+	Short: "Print the IAM policy the installing identity needs",
+	Long: `Prints a minimal IAM policy document for the installer user, with one
+statement per step. The actions depend on the configuration: private bucket
+and CloudFront, byoIAM, permissions boundary, KMS key, preflight checks and,
+once install-config.yaml exists, existing subnets.`,
+	Run: runPrintRequiredPolicy,
+}
+
+func init() {
+	rootCmd.AddCommand(printRequiredPolicyCmd)
+
+	printRequiredPolicyCmd.Flags().StringVar(&policyReleaseImage, "release-image", "", "OpenShift release image (to find install-config.yaml)")
+	printRequiredPolicyCmd.Flags().StringVar(&policyOutput, "output", "", "Write the policy to a file instead of stdout")
+}
+
+func runPrintRequiredPolicy(cmd *cobra.Command, args []string) {
+	// Keep stdout for the policy document
+	log := logger.New(logger.Level(getLogLevel()), os.Stderr)
+
+	cfg := loadConfig(log)
+	if policyReleaseImage != "" {
+		cfg.ReleaseImage = policyReleaseImage
+	}
+	var installConfig *util.InstallConfig
+	if versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage); err == nil {
+		installConfig, _ = util.LoadInstallConfig(versionArch)
+	}
+
+	policy, err := steps.RequiredPolicy(steps.RequiredPermissions(cfg, installConfig))
+	checkErr(err)
+
+	size := len(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(policy)))
+	if size > managedPolicySizeLimit {
+		log.Info(fmt.Sprintf("⚠  The policy has %d characters, more than the %d of a managed policy: split its statements into several policies",
+			size, managedPolicySizeLimit))
+	}
+
+	if policyOutput == "" {
+		fmt.Println(string(policy))
+		return
+	}
+	checkErr(os.WriteFile(policyOutput, append(policy, '\n'), 0644))
+	log.Info(fmt.Sprintf("✓ Policy written to %s", policyOutput))
+}
@@ -2,22 +2,190 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	ReleaseImage    string `yaml:"releaseImage"`
-	ClusterName     string `yaml:"clusterName"`
-	AwsRegion       string `yaml:"awsRegion"`
-	AwsProfile      string `yaml:"awsProfile"`
-	PullSecretPath  string `yaml:"pullSecretPath"`
-	PrivateBucket   bool   `yaml:"privateBucket"`
-	OutputDir       string `yaml:"outputDir"`
-	StartFromStep   int    `yaml:"startFromStep"`
-	ConfirmEachStep bool   `yaml:"confirmEachStep"`
-	InstanceType    string `yaml:"instanceType"`
+	ReleaseImage    string            `yaml:"releaseImage"`
+	ClusterName     string            `yaml:"clusterName"`
+	AwsRegion       string            `yaml:"awsRegion"`
+	AwsProfile      string            `yaml:"awsProfile"`
+	PullSecretPath  string            `yaml:"pullSecretPath"`
+	PrivateBucket   bool              `yaml:"privateBucket"`
+	OutputDir       string            `yaml:"outputDir"`
+	StartFromStep   int               `yaml:"startFromStep"`
+	ConfirmEachStep bool              `yaml:"confirmEachStep"`
+	InstanceType    string            `yaml:"instanceType"`
+	Proxy           ProxyConfig       `yaml:"proxy"`
+	TrustBundle     string            `yaml:"trustBundle"`
+	Hardening       string            `yaml:"hardening"`
+	KMSKeyARN       string            `yaml:"kmsKeyARN"`
+	Compute         ComputeConfig     `yaml:"compute"`
+	ExtraManifests  []string          `yaml:"extraManifests"`
+	ManifestPatches []ManifestPatch   `yaml:"manifestPatches"`
+	ReviewManifests bool              `yaml:"reviewManifests"`
+	BYOIAM          *BYOIAMConfig     `yaml:"byoIAM"`
+	SigningKey      *SigningKey       `yaml:"signingKey"`
+	Tags            map[string]string `yaml:"tags"`
+	// PermissionsBoundaryArn is the IAM policy attached as permissions boundary to the roles of Step 7
+	PermissionsBoundaryArn string `yaml:"permissionsBoundaryArn"`
+	// CredentialExpiry is checked against the lifetime left to the AWS credentials before Step 10
+	CredentialExpiry CredentialExpiryConfig `yaml:"credentialExpiry"`
+	// Guardrails are checked after the credential validation and again on install-config.yaml
+	Guardrails GuardrailsConfig `yaml:"guardrails"`
+	// AwsProfiles selects other profiles than awsProfile for the IAM resources, the infrastructure and the DNS zone
+	AwsProfiles AWSProfilesConfig `yaml:"awsProfiles"`
+	// SkipPreflight disables the IAM permission simulation before Step 1 and the AWS preflight checks before Step 7
+	SkipPreflight bool `yaml:"skipPreflight"`
+}
+
+// AWSProfilesConfig holds the profiles used when IAM changes, the cluster
+// infrastructure and the Route53 hosted zone are in different accounts or
+// need different roles. IAM and Infra default to awsProfile.
+type AWSProfilesConfig struct {
+	// IAM creates and deletes the OIDC provider, bucket and IAM roles (Step 7, ccoctl aws delete)
+	IAM string `yaml:"iam"`
+	// Infra creates and destroys the cluster (Steps 4 and 10, openshift-install destroy)
+	Infra string `yaml:"infra"`
+	// DNS assumes a role in the account of the hosted zone, written to platform.aws.hostedZoneRole
+	DNS string `yaml:"dns"`
+}
+
+// IAMProfile returns the profile for the IAM resources
+func (c *Config) IAMProfile() string {
+	if c.AwsProfiles.IAM != "" {
+		return c.AwsProfiles.IAM
+	}
+	return c.AwsProfile
+}
+
+// InfraProfile returns the profile for the cluster infrastructure
+func (c *Config) InfraProfile() string {
+	if c.AwsProfiles.Infra != "" {
+		return c.AwsProfiles.Infra
+	}
+	return c.AwsProfile
+}
+
+// Profiles returns the distinct profiles the installation uses
+func (c *Config) Profiles() []string {
+	var profiles []string
+	for _, profile := range []string{c.InfraProfile(), c.IAMProfile(), c.AwsProfiles.DNS} {
+		if profile != "" && !contains(profiles, profile) {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// CredentialExpiryConfig sets what happens when the AWS credentials would
+// expire during the cluster deployment
+type CredentialExpiryConfig struct {
+	// MinLifetime is the lifetime the credentials must have left before Step 10, e.g. 45m
+	MinLifetime string `yaml:"minLifetime"`
+	// Action is refuse or warn
+	Action string `yaml:"action"`
+}
+
+// Credential expiry actions
+const (
+	CredentialExpiryRefuse = "refuse"
+	CredentialExpiryWarn   = "warn"
+)
+
+// Threshold returns MinLifetime as a duration, zero when it is not valid
+func (c CredentialExpiryConfig) Threshold() time.Duration {
+	d, _ := time.ParseDuration(c.MinLifetime)
+	return d
+}
+
+// SigningKey is an existing service-account signing key pair, reused instead
+// of generating a new one so that the OIDC provider keys survive reinstalls
+type SigningKey struct {
+	PrivateKey string `yaml:"privateKey"`
+	PublicKey  string `yaml:"publicKey"`
+}
+
+// BYOIAMConfig describes IAM resources created outside the installer. When
+// set, Step 7 renders the ccoctl output itself instead of running ccoctl.
+type BYOIAMConfig struct {
+	// Roles maps CredentialsRequest names to existing IAM role ARNs
+	Roles map[string]string `yaml:"roles"`
+	// IssuerURL is the URL of the existing OIDC issuer
+	IssuerURL string `yaml:"issuerURL"`
+	// SigningKey is the private key matching the public key served by the issuer
+	SigningKey string `yaml:"signingKey"`
+}
+
+// Manifest patch types
+const (
+	PatchTypeJSON6902       = "json6902"
+	PatchTypeStrategicMerge = "strategic-merge"
+)
+
+// ManifestPatch is a patch applied to a manifest generated in Step 6
+type ManifestPatch struct {
+	// Target is the manifest path relative to the version directory, e.g. openshift/99_foo.yaml
+	Target string `yaml:"target"`
+	// Type is json6902 or strategic-merge
+	Type string `yaml:"type"`
+	// Patch is the path of the file holding the patch
+	Patch string `yaml:"patch"`
+}
+
+// ComputeConfig holds settings for the compute (worker) machine pools
+type ComputeConfig struct {
+	// Spot runs the workers on spot instances when set
+	Spot *SpotConfig `yaml:"spot"`
+}
+
+// SpotConfig holds the spot market options for the workers. An empty MaxPrice
+// caps the price at the on-demand price.
+type SpotConfig struct {
+	MaxPrice string `yaml:"maxPrice"`
+}
+
+// Hardening profiles
+const (
+	HardeningBaseline = "baseline"
+	HardeningStrict   = "strict"
+)
+
+// ProxyConfig holds the cluster-wide proxy settings. They are exported to
+// every command the wrapper runs and written to install-config.yaml.
+type ProxyConfig struct {
+	HTTPProxy  string `yaml:"httpProxy"`
+	HTTPSProxy string `yaml:"httpsProxy"`
+	NoProxy    string `yaml:"noProxy"`
+}
+
+// IsSet reports whether any proxy setting is configured
+func (p ProxyConfig) IsSet() bool {
+	return p.HTTPProxy != "" || p.HTTPSProxy != "" || p.NoProxy != ""
+}
+
+// EnvVars returns the proxy settings as environment variables. Both upper and
+// lower case names are set since oc, ccoctl and openshift-install differ in
+// which ones they honour.
+func (p ProxyConfig) EnvVars() []string {
+	var env []string
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		env = append(env, fmt.Sprintf("%s=%s", name, value), fmt.Sprintf("%s=%s", strings.ToLower(name), value))
+	}
+	add("HTTP_PROXY", p.HTTPProxy)
+	add("HTTPS_PROXY", p.HTTPSProxy)
+	add("NO_PROXY", p.NoProxy)
+	return env
 }
 
 // LoadFromFile loads configuration from a YAML file
@@ -47,6 +215,26 @@ func LoadFromEnv() *Config {
 		OutputDir:       os.Getenv("OPENSHIFT_STS_OUTPUT_DIR"),
 		ConfirmEachStep: os.Getenv("OPENSHIFT_STS_CONFIRM_EACH_STEP") == "true",
 		InstanceType:    os.Getenv("OPENSHIFT_STS_INSTANCE_TYPE"),
+		Proxy: ProxyConfig{
+			HTTPProxy:  os.Getenv("OPENSHIFT_STS_HTTP_PROXY"),
+			HTTPSProxy: os.Getenv("OPENSHIFT_STS_HTTPS_PROXY"),
+			NoProxy:    os.Getenv("OPENSHIFT_STS_NO_PROXY"),
+		},
+		TrustBundle:            os.Getenv("OPENSHIFT_STS_TRUST_BUNDLE"),
+		Hardening:              os.Getenv("OPENSHIFT_STS_HARDENING"),
+		KMSKeyARN:              os.Getenv("OPENSHIFT_STS_KMS_KEY_ARN"),
+		ReviewManifests:        os.Getenv("OPENSHIFT_STS_REVIEW_MANIFESTS") == "true",
+		SkipPreflight:          os.Getenv("OPENSHIFT_STS_SKIP_PREFLIGHT") == "true",
+		PermissionsBoundaryArn: os.Getenv("OPENSHIFT_STS_PERMISSIONS_BOUNDARY_ARN"),
+		CredentialExpiry: CredentialExpiryConfig{
+			MinLifetime: os.Getenv("OPENSHIFT_STS_MIN_CREDENTIAL_LIFETIME"),
+			Action:      os.Getenv("OPENSHIFT_STS_CREDENTIAL_EXPIRY_ACTION"),
+		},
+		AwsProfiles: AWSProfilesConfig{
+			IAM:   os.Getenv("OPENSHIFT_STS_AWS_PROFILE_IAM"),
+			Infra: os.Getenv("OPENSHIFT_STS_AWS_PROFILE_INFRA"),
+			DNS:   os.Getenv("OPENSHIFT_STS_AWS_PROFILE_DNS"),
+		},
 	}
 }
 
@@ -64,6 +252,15 @@ func (c *Config) Merge(other *Config) {
 	if other.AwsProfile != "" {
 		c.AwsProfile = other.AwsProfile
 	}
+	if other.AwsProfiles.IAM != "" {
+		c.AwsProfiles.IAM = other.AwsProfiles.IAM
+	}
+	if other.AwsProfiles.Infra != "" {
+		c.AwsProfiles.Infra = other.AwsProfiles.Infra
+	}
+	if other.AwsProfiles.DNS != "" {
+		c.AwsProfiles.DNS = other.AwsProfiles.DNS
+	}
 	if other.PullSecretPath != "" {
 		c.PullSecretPath = other.PullSecretPath
 	}
@@ -82,6 +279,72 @@ func (c *Config) Merge(other *Config) {
 	if other.InstanceType != "" {
 		c.InstanceType = other.InstanceType
 	}
+	if other.Proxy.HTTPProxy != "" {
+		c.Proxy.HTTPProxy = other.Proxy.HTTPProxy
+	}
+	if other.Proxy.HTTPSProxy != "" {
+		c.Proxy.HTTPSProxy = other.Proxy.HTTPSProxy
+	}
+	if other.Proxy.NoProxy != "" {
+		c.Proxy.NoProxy = other.Proxy.NoProxy
+	}
+	if other.TrustBundle != "" {
+		c.TrustBundle = other.TrustBundle
+	}
+	if other.Hardening != "" {
+		c.Hardening = other.Hardening
+	}
+	if other.KMSKeyARN != "" {
+		c.KMSKeyARN = other.KMSKeyARN
+	}
+	if other.Compute.Spot != nil {
+		c.Compute.Spot = other.Compute.Spot
+	}
+	if len(other.ExtraManifests) > 0 {
+		c.ExtraManifests = other.ExtraManifests
+	}
+	if len(other.ManifestPatches) > 0 {
+		c.ManifestPatches = other.ManifestPatches
+	}
+	if other.ReviewManifests {
+		c.ReviewManifests = other.ReviewManifests
+	}
+	if other.SkipPreflight {
+		c.SkipPreflight = other.SkipPreflight
+	}
+	if other.BYOIAM != nil {
+		c.BYOIAM = other.BYOIAM
+	}
+	if other.SigningKey != nil {
+		c.SigningKey = other.SigningKey
+	}
+	if len(other.Tags) > 0 {
+		c.Tags = other.Tags
+	}
+	if other.PermissionsBoundaryArn != "" {
+		c.PermissionsBoundaryArn = other.PermissionsBoundaryArn
+	}
+	if other.CredentialExpiry.MinLifetime != "" {
+		c.CredentialExpiry.MinLifetime = other.CredentialExpiry.MinLifetime
+	}
+	if other.CredentialExpiry.Action != "" {
+		c.CredentialExpiry.Action = other.CredentialExpiry.Action
+	}
+	if len(other.Guardrails.AllowedAccounts) > 0 {
+		c.Guardrails.AllowedAccounts = other.Guardrails.AllowedAccounts
+	}
+	if len(other.Guardrails.AllowedRegions) > 0 {
+		c.Guardrails.AllowedRegions = other.Guardrails.AllowedRegions
+	}
+	if other.Guardrails.ClusterNamePattern != "" {
+		c.Guardrails.ClusterNamePattern = other.Guardrails.ClusterNamePattern
+	}
+	if other.Guardrails.MaxReplicas != 0 {
+		c.Guardrails.MaxReplicas = other.Guardrails.MaxReplicas
+	}
+	if len(other.Guardrails.ForbiddenInstanceFamilies) > 0 {
+		c.Guardrails.ForbiddenInstanceFamilies = other.Guardrails.ForbiddenInstanceFamilies
+	}
 }
 
 // ValidateConfig validates that required fields are set
@@ -90,6 +353,103 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("release image is required")
 	}
 	// ClusterName and AwsRegion are now optional - they can be read from install-config.yaml
+	if err := validateProxyURL("httpProxy", cfg.Proxy.HTTPProxy); err != nil {
+		return err
+	}
+	if err := validateProxyURL("httpsProxy", cfg.Proxy.HTTPSProxy); err != nil {
+		return err
+	}
+	for _, patch := range cfg.ManifestPatches {
+		if patch.Type != PatchTypeJSON6902 && patch.Type != PatchTypeStrategicMerge {
+			return fmt.Errorf("manifest patch for %s has unknown type %q (expected %q or %q)",
+				patch.Target, patch.Type, PatchTypeJSON6902, PatchTypeStrategicMerge)
+		}
+		if patch.Target == "" || filepath.IsAbs(patch.Target) || strings.HasPrefix(filepath.Clean(patch.Target), "..") {
+			return fmt.Errorf("manifest patch target %q must be a path relative to the version directory", patch.Target)
+		}
+		if patch.Patch == "" {
+			return fmt.Errorf("manifest patch for %s has no patch file", patch.Target)
+		}
+	}
+	if cfg.BYOIAM != nil {
+		if err := validateBYOIAM(cfg.BYOIAM); err != nil {
+			return err
+		}
+	}
+	if cfg.SigningKey != nil {
+		if cfg.SigningKey.PrivateKey == "" || cfg.SigningKey.PublicKey == "" {
+			return fmt.Errorf("signingKey requires both privateKey and publicKey")
+		}
+		if cfg.BYOIAM != nil {
+			return fmt.Errorf("signingKey cannot be combined with byoIAM, use byoIAM.signingKey instead")
+		}
+	}
+	if cfg.PermissionsBoundaryArn != "" && !policyARNPattern.MatchString(cfg.PermissionsBoundaryArn) {
+		return fmt.Errorf("permissionsBoundaryArn must be an IAM policy ARN, got %q", cfg.PermissionsBoundaryArn)
+	}
+	if d, err := time.ParseDuration(cfg.CredentialExpiry.MinLifetime); cfg.CredentialExpiry.MinLifetime != "" && (err != nil || d < 0) {
+		return fmt.Errorf("credentialExpiry minLifetime must be a duration like 60m, got %q", cfg.CredentialExpiry.MinLifetime)
+	}
+	switch cfg.CredentialExpiry.Action {
+	case "", CredentialExpiryRefuse, CredentialExpiryWarn:
+	default:
+		return fmt.Errorf("unknown credentialExpiry action %q (expected %q or %q)", cfg.CredentialExpiry.Action, CredentialExpiryRefuse, CredentialExpiryWarn)
+	}
+	if err := validateGuardrails(&cfg.Guardrails); err != nil {
+		return err
+	}
+	for key, value := range cfg.Tags {
+		if key == "" || len(key) > 128 || len(value) > 256 {
+			return fmt.Errorf("tag %q is invalid: keys are 1-128 and values up to 256 characters", key)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") || strings.HasPrefix(key, "kubernetes.io/cluster/") {
+			return fmt.Errorf("tag %q uses a reserved prefix", key)
+		}
+	}
+	switch cfg.Hardening {
+	case "", HardeningBaseline:
+	case HardeningStrict:
+		if cfg.KMSKeyARN == "" {
+			return fmt.Errorf("hardening profile %q requires kmsKeyARN for encrypting root volumes", HardeningStrict)
+		}
+	default:
+		return fmt.Errorf("unknown hardening profile %q (expected %q or %q)", cfg.Hardening, HardeningBaseline, HardeningStrict)
+	}
+	return nil
+}
+
+func validateProxyURL(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("proxy %s must be a URL like http://proxy.example.com:3128, got %q", field, value)
+	}
+	return nil
+}
+
+var (
+	roleARNPattern   = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+`)
+	policyARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::(\d{12}|aws):policy/.+`)
+)
+
+func validateBYOIAM(byo *BYOIAMConfig) error {
+	u, err := url.Parse(byo.IssuerURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("byoIAM issuerURL must be an https URL, got %q", byo.IssuerURL)
+	}
+	if byo.SigningKey == "" {
+		return fmt.Errorf("byoIAM signingKey is required")
+	}
+	if len(byo.Roles) == 0 {
+		return fmt.Errorf("byoIAM roles must map each CredentialsRequest to a role ARN")
+	}
+	for name, arn := range byo.Roles {
+		if !roleARNPattern.MatchString(arn) {
+			return fmt.Errorf("byoIAM role for %s is not an IAM role ARN: %q", name, arn)
+		}
+	}
 	return nil
 }
 
@@ -104,6 +464,14 @@ func (c *Config) SetDefaults() {
 	if c.AwsProfile == "" {
 		c.AwsProfile = "default"
 	}
+	if c.CredentialExpiry.MinLifetime == "" {
+		// Step 10 usually takes 40 minutes or more; the one-hour default
+		// session of assumed roles and SSO must still pass
+		c.CredentialExpiry.MinLifetime = "45m"
+	}
+	if c.CredentialExpiry.Action == "" {
+		c.CredentialExpiry.Action = CredentialExpiryRefuse
+	}
 	if c.InstanceType == "" {
 		c.InstanceType = "m5.4xlarge"
 	}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadConfigFromFile(t *testing.T) {
@@ -144,3 +145,142 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyEnvVars(t *testing.T) {
+	proxy := ProxyConfig{
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    ".example.com,10.0.0.0/16",
+	}
+
+	env := proxy.EnvVars()
+
+	expected := []string{
+		"HTTPS_PROXY=http://proxy.example.com:3128",
+		"https_proxy=http://proxy.example.com:3128",
+		"NO_PROXY=.example.com,10.0.0.0/16",
+		"no_proxy=.example.com,10.0.0.0/16",
+	}
+	if len(env) != len(expected) {
+		t.Fatalf("Expected %d environment variables, got %d: %v", len(expected), len(env), env)
+	}
+	for i := range expected {
+		if env[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], env[i])
+		}
+	}
+
+	if len((ProxyConfig{}).EnvVars()) != 0 {
+		t.Error("Empty proxy config should not produce environment variables")
+	}
+}
+
+func TestLoadSpotConfigFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "openshift-sts-installer.yaml")
+	os.WriteFile(configPath, []byte("compute:\n  spot:\n    maxPrice: 0.25\n"), 0644)
+
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Compute.Spot == nil || cfg.Compute.Spot.MaxPrice != "0.25" {
+		t.Errorf("Expected spot maxPrice 0.25, got %+v", cfg.Compute.Spot)
+	}
+}
+
+func TestValidateBYOIAM(t *testing.T) {
+	valid := func() *BYOIAMConfig {
+		return &BYOIAMConfig{
+			Roles:      map[string]string{"openshift-ingress": "arn:aws:iam::123456789012:role/ingress"},
+			IssuerURL:  "https://oidc.example.com",
+			SigningKey: "signer.key",
+		}
+	}
+
+	cfg := &Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", BYOIAM: valid()}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid byoIAM config, got: %v", err)
+	}
+
+	invalid := map[string]func(*BYOIAMConfig){
+		"http issuer":    func(b *BYOIAMConfig) { b.IssuerURL = "http://oidc.example.com" },
+		"no signing key": func(b *BYOIAMConfig) { b.SigningKey = "" },
+		"no roles":       func(b *BYOIAMConfig) { b.Roles = nil },
+		"not a role ARN": func(b *BYOIAMConfig) { b.Roles["openshift-ingress"] = "arn:aws:iam::123456789012:user/ingress" },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			byo := valid()
+			mutate(byo)
+			cfg := &Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", BYOIAM: byo}
+			if err := ValidateConfig(cfg); err == nil {
+				t.Error("Expected validation error but got none")
+			}
+		})
+	}
+}
+
+func TestCredentialExpiryConfig(t *testing.T) {
+	cfg := &Config{}
+	cfg.SetDefaults()
+	if cfg.CredentialExpiry.Threshold() != 45*time.Minute || cfg.CredentialExpiry.Action != CredentialExpiryRefuse {
+		t.Errorf("Unexpected credentialExpiry defaults %+v", cfg.CredentialExpiry)
+	}
+
+	valid := []CredentialExpiryConfig{{}, {MinLifetime: "90m", Action: CredentialExpiryWarn}, {MinLifetime: "0s"}}
+	for _, expiry := range valid {
+		cfg := &Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", CredentialExpiry: expiry}
+		if err := ValidateConfig(cfg); err != nil {
+			t.Errorf("Expected %+v to be valid, got: %v", expiry, err)
+		}
+	}
+
+	invalid := []CredentialExpiryConfig{{MinLifetime: "1 hour"}, {MinLifetime: "-5m"}, {Action: "ignore"}}
+	for _, expiry := range invalid {
+		cfg := &Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", CredentialExpiry: expiry}
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be invalid", expiry)
+		}
+	}
+}
+
+func TestAWSProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "openshift-sts-installer.yaml")
+	configContent := `awsProfile: default
+awsProfiles:
+  iam: security
+  dns: network
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.IAMProfile() != "security" || cfg.InfraProfile() != "default" {
+		t.Errorf("Expected IAM profile security and infra profile default, got %q and %q", cfg.IAMProfile(), cfg.InfraProfile())
+	}
+	cfg.Merge(&Config{AwsProfiles: AWSProfilesConfig{Infra: "workload"}})
+	if cfg.InfraProfile() != "workload" || cfg.IAMProfile() != "security" {
+		t.Errorf("Merge should only override the infra profile, got %+v", cfg.AwsProfiles)
+	}
+
+	profiles := cfg.Profiles()
+	want := []string{"workload", "security", "network"}
+	if len(profiles) != len(want) {
+		t.Fatalf("Expected profiles %v, got %v", want, profiles)
+	}
+	for i := range want {
+		if profiles[i] != want[i] {
+			t.Errorf("Expected profiles %v, got %v", want, profiles)
+		}
+	}
+
+	// Without awsProfiles every step uses awsProfile
+	single := &Config{AwsProfile: "default"}
+	if p := single.Profiles(); len(p) != 1 || p[0] != "default" {
+		t.Errorf("Expected a single profile, got %v", p)
+	}
+}
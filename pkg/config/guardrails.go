@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GuardrailsConfig restricts the accounts, regions and cluster shapes the
+// installer accepts. Empty fields are not checked.
+type GuardrailsConfig struct {
+	// AllowedAccounts are the AWS account IDs the credentials may belong to
+	AllowedAccounts []string `yaml:"allowedAccounts"`
+	AllowedRegions  []string `yaml:"allowedRegions"`
+	// ClusterNamePattern is a regular expression the whole cluster name must match
+	ClusterNamePattern string `yaml:"clusterNamePattern"`
+	// MaxReplicas is the largest number of replicas of a machine pool
+	MaxReplicas int `yaml:"maxReplicas"`
+	// ForbiddenInstanceFamilies are instance families like p4d or x2iedn
+	ForbiddenInstanceFamilies []string `yaml:"forbiddenInstanceFamilies"`
+}
+
+// Guardrail names, as given to --override-guardrail
+const (
+	GuardrailAccount        = "account"
+	GuardrailRegion         = "region"
+	GuardrailClusterName    = "cluster-name"
+	GuardrailReplicas       = "replicas"
+	GuardrailInstanceFamily = "instance-family"
+)
+
+// Guardrails lists every guardrail name
+var Guardrails = []string{GuardrailAccount, GuardrailRegion, GuardrailClusterName, GuardrailReplicas, GuardrailInstanceFamily}
+
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// GuardrailTarget is what the guardrails are checked against. Empty fields
+// are not known yet and are not checked.
+type GuardrailTarget struct {
+	Account      string
+	Region       string
+	ClusterName  string
+	MachinePools []GuardrailPool
+}
+
+// GuardrailPool is a machine pool of the cluster. A nil Replicas is not
+// checked.
+type GuardrailPool struct {
+	Name         string
+	Replicas     *int
+	InstanceType string
+}
+
+// GuardrailViolation is a guardrail the target does not respect
+type GuardrailViolation struct {
+	Guardrail string
+	Message   string
+}
+
+func (v GuardrailViolation) Error() string {
+	return fmt.Sprintf("guardrail %s: %s", v.Guardrail, v.Message)
+}
+
+// Check returns the guardrails the target violates
+func (g *GuardrailsConfig) Check(target GuardrailTarget) []GuardrailViolation {
+	var violations []GuardrailViolation
+	if target.Account != "" && len(g.AllowedAccounts) > 0 && !contains(g.AllowedAccounts, target.Account) {
+		violations = append(violations, GuardrailViolation{GuardrailAccount,
+			fmt.Sprintf("AWS account %s is not one of the allowed accounts %s", target.Account, strings.Join(g.AllowedAccounts, ", "))})
+	}
+	if target.Region != "" && len(g.AllowedRegions) > 0 && !contains(g.AllowedRegions, target.Region) {
+		violations = append(violations, GuardrailViolation{GuardrailRegion,
+			fmt.Sprintf("region %s is not one of the allowed regions %s", target.Region, strings.Join(g.AllowedRegions, ", "))})
+	}
+	if target.ClusterName != "" && g.ClusterNamePattern != "" {
+		if pattern, err := regexp.Compile("^(?:" + g.ClusterNamePattern + ")$"); err == nil && !pattern.MatchString(target.ClusterName) {
+			violations = append(violations, GuardrailViolation{GuardrailClusterName,
+				fmt.Sprintf("cluster name %q does not match %s", target.ClusterName, g.ClusterNamePattern)})
+		}
+	}
+	for _, pool := range target.MachinePools {
+		if g.MaxReplicas > 0 && pool.Replicas != nil && *pool.Replicas > g.MaxReplicas {
+			violations = append(violations, GuardrailViolation{GuardrailReplicas,
+				fmt.Sprintf("machine pool %s has %d replicas, more than the maximum of %d", pool.Name, *pool.Replicas, g.MaxReplicas)})
+		}
+		family, _, _ := strings.Cut(pool.InstanceType, ".")
+		for _, forbidden := range g.ForbiddenInstanceFamilies {
+			if family != "" && strings.EqualFold(family, forbidden) {
+				violations = append(violations, GuardrailViolation{GuardrailInstanceFamily,
+					fmt.Sprintf("machine pool %s uses %s, of the forbidden instance family %s", pool.Name, pool.InstanceType, forbidden)})
+			}
+		}
+	}
+	return violations
+}
+
+// ValidateGuardrailOverrides checks that every override names a guardrail
+func ValidateGuardrailOverrides(overrides []string) error {
+	for _, name := range overrides {
+		if !contains(Guardrails, name) {
+			return fmt.Errorf("unknown guardrail %q (expected one of %s)", name, strings.Join(Guardrails, ", "))
+		}
+	}
+	return nil
+}
+
+func validateGuardrails(g *GuardrailsConfig) error {
+	for _, account := range g.AllowedAccounts {
+		if !accountIDPattern.MatchString(account) {
+			return fmt.Errorf("guardrails allowedAccounts must be 12-digit account IDs, got %q", account)
+		}
+	}
+	if g.ClusterNamePattern != "" {
+		if _, err := regexp.Compile(g.ClusterNamePattern); err != nil {
+			return fmt.Errorf("guardrails clusterNamePattern is not a valid regular expression: %w", err)
+		}
+	}
+	if g.MaxReplicas < 0 {
+		return fmt.Errorf("guardrails maxReplicas must not be negative, got %d", g.MaxReplicas)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestGuardrailsCheck(t *testing.T) {
+	guardrails := &GuardrailsConfig{
+		AllowedAccounts:           []string{"111111111111"},
+		AllowedRegions:            []string{"us-east-2", "eu-west-1"},
+		ClusterNamePattern:        "dev-[a-z0-9-]+",
+		MaxReplicas:               5,
+		ForbiddenInstanceFamilies: []string{"p4d", "x2iedn"},
+	}
+	three, six := 3, 6
+
+	allowed := GuardrailTarget{
+		Account:     "111111111111",
+		Region:      "us-east-2",
+		ClusterName: "dev-test",
+		MachinePools: []GuardrailPool{
+			{Name: "master", Replicas: &three, InstanceType: "m5.xlarge"},
+			{Name: "worker", InstanceType: "m5.4xlarge"},
+		},
+	}
+	if violations := guardrails.Check(allowed); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+	if violations := guardrails.Check(GuardrailTarget{}); len(violations) != 0 {
+		t.Errorf("Expected unknown fields not to be checked, got %v", violations)
+	}
+
+	tests := map[string]GuardrailTarget{
+		GuardrailAccount: {Account: "222222222222"},
+		GuardrailRegion:  {Region: "us-east-1"},
+		// The pattern must match the whole name
+		GuardrailClusterName:    {ClusterName: "prod-dev-test"},
+		GuardrailReplicas:       {MachinePools: []GuardrailPool{{Name: "worker", Replicas: &six}}},
+		GuardrailInstanceFamily: {MachinePools: []GuardrailPool{{Name: "gpu", InstanceType: "P4D.24xlarge"}}},
+	}
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			violations := guardrails.Check(target)
+			if len(violations) != 1 || violations[0].Guardrail != name {
+				t.Errorf("Expected a %s violation, got %v", name, violations)
+			}
+		})
+	}
+}
+
+func TestValidateGuardrails(t *testing.T) {
+	invalid := map[string]GuardrailsConfig{
+		"short account":    {AllowedAccounts: []string{"1234"}},
+		"bad pattern":      {ClusterNamePattern: "dev-("},
+		"negative maximum": {MaxReplicas: -1},
+	}
+	for name, guardrails := range invalid {
+		t.Run(name, func(t *testing.T) {
+			cfg := &Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", Guardrails: guardrails}
+			if err := ValidateConfig(cfg); err == nil {
+				t.Error("Expected validation error but got none")
+			}
+		})
+	}
+
+	if err := ValidateGuardrailOverrides([]string{GuardrailRegion, GuardrailReplicas}); err != nil {
+		t.Errorf("Expected valid overrides, got %v", err)
+	}
+	if err := ValidateGuardrailOverrides([]string{"everything"}); err == nil {
+		t.Error("Expected an error for an unknown guardrail")
+	}
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"crypto"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 // ValidatePullSecret checks if the pull secret file exists and is valid JSON
@@ -26,6 +31,112 @@ func ValidatePullSecret(path string) error {
 	return nil
 }
 
+// ValidateTrustBundle checks that the trust bundle file contains at least one PEM certificate
+func ValidateTrustBundle(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read trust bundle: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("trust bundle %s does not contain a PEM encoded certificate", path)
+	}
+
+	return nil
+}
+
+// ValidateSigningKeyPair checks that the private and public keys are PEM
+// encoded and belong to the same key pair
+func ValidateSigningKeyPair(privatePath, publicPath string) error {
+	privateData, err := os.ReadFile(privatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read private signing key: %w", err)
+	}
+	publicData, err := os.ReadFile(publicPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public signing key: %w", err)
+	}
+
+	privateBlock, _ := pem.Decode(privateData)
+	if privateBlock == nil {
+		return fmt.Errorf("%s is not a PEM encoded private key", privatePath)
+	}
+	var privateKey interface{}
+	if privateKey, err = x509.ParsePKCS1PrivateKey(privateBlock.Bytes); err != nil {
+		if privateKey, err = x509.ParsePKCS8PrivateKey(privateBlock.Bytes); err != nil {
+			return fmt.Errorf("failed to parse private signing key %s: %w", privatePath, err)
+		}
+	}
+
+	publicBlock, _ := pem.Decode(publicData)
+	if publicBlock == nil {
+		return fmt.Errorf("%s is not a PEM encoded public key", publicPath)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(publicBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public signing key %s: %w", publicPath, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported private signing key type in %s", privatePath)
+	}
+	matcher, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !matcher.Equal(publicKey) {
+		return fmt.Errorf("public key %s does not match private key %s", publicPath, privatePath)
+	}
+
+	return nil
+}
+
+// ValidateNoProxy checks that every cluster network CIDR is covered by an entry
+// of the comma-separated noProxy list, either verbatim or by an enclosing CIDR.
+// Without this, node and pod traffic inside the cluster would go through the proxy.
+func ValidateNoProxy(noProxy string, networks []string) error {
+	var entries []string
+	for _, entry := range strings.Split(noProxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	var missing []string
+	for _, network := range networks {
+		if !noProxyCovers(entries, network) {
+			missing = append(missing, network)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("noProxy does not include the cluster networks %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func noProxyCovers(entries []string, network string) bool {
+	_, target, err := net.ParseCIDR(network)
+	for _, entry := range entries {
+		if entry == "*" || entry == network {
+			return true
+		}
+		if err != nil {
+			continue
+		}
+		_, candidate, cerr := net.ParseCIDR(entry)
+		if cerr != nil {
+			continue
+		}
+		candidateOnes, _ := candidate.Mask.Size()
+		targetOnes, _ := target.Mask.Size()
+		if candidateOnes <= targetOnes && candidate.Contains(target.IP) {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckPrerequisites validates that required tools are available
 func CheckPrerequisites() error {
 	// Check for oc command
@@ -35,3 +146,29 @@ func CheckPrerequisites() error {
 
 	return nil
 }
+
+// AWSCLIFeatures returns the configured features that run the aws CLI
+func AWSCLIFeatures(cfg *Config) []string {
+	var features []string
+	if len(cfg.Tags) > 0 {
+		features = append(features, "tags")
+	}
+	if cfg.PermissionsBoundaryArn != "" {
+		features = append(features, "permissionsBoundaryArn")
+	}
+	if !cfg.SkipPreflight {
+		features = append(features, "preflight", "IAM permission simulation")
+	}
+	return features
+}
+
+// CheckAWSCLI validates that the aws CLI is available when a feature needs it
+func CheckAWSCLI(features ...string) error {
+	if len(features) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("aws"); err != nil {
+		return fmt.Errorf("'aws' command not found in PATH, it is needed by %s. Please install the AWS CLI", strings.Join(features, ", "))
+	}
+	return nil
+}
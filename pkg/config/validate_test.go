@@ -1,8 +1,13 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -60,3 +65,129 @@ func TestValidatePullSecretEmptyPath(t *testing.T) {
 		t.Error("Expected error for empty path")
 	}
 }
+
+func TestValidateNoProxy(t *testing.T) {
+	networks := []string{"10.0.0.0/16", "10.128.0.0/14", "172.30.0.0/16"}
+
+	tests := []struct {
+		name        string
+		noProxy     string
+		shouldError bool
+	}{
+		{
+			name:        "all networks listed",
+			noProxy:     ".example.com,10.0.0.0/16,10.128.0.0/14,172.30.0.0/16",
+			shouldError: false,
+		},
+		{
+			name:        "enclosing CIDRs",
+			noProxy:     "10.0.0.0/8, 172.16.0.0/12",
+			shouldError: false,
+		},
+		{
+			name:        "wildcard",
+			noProxy:     "*",
+			shouldError: false,
+		},
+		{
+			name:        "service network missing",
+			noProxy:     "10.0.0.0/8",
+			shouldError: true,
+		},
+		{
+			name:        "empty",
+			noProxy:     "",
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNoProxy(tt.noProxy, networks)
+			if tt.shouldError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTrustBundle(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	valid := filepath.Join(tmpDir, "ca.pem")
+	os.WriteFile(valid, []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"), 0644)
+	if err := ValidateTrustBundle(valid); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+
+	invalid := filepath.Join(tmpDir, "not-a-cert.pem")
+	os.WriteFile(invalid, []byte("hello"), 0644)
+	if err := ValidateTrustBundle(invalid); err == nil {
+		t.Error("Expected error for file without a certificate")
+	}
+}
+
+func writeKeyPair(t *testing.T, dir, name string) (privatePath, publicPath string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath = filepath.Join(dir, name+".private")
+	publicPath = filepath.Join(dir, name+".public")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+	return privatePath, publicPath
+}
+
+func TestValidateSigningKeyPair(t *testing.T) {
+	tmpDir := t.TempDir()
+	private1, public1 := writeKeyPair(t, tmpDir, "one")
+	_, public2 := writeKeyPair(t, tmpDir, "two")
+
+	if err := ValidateSigningKeyPair(private1, public1); err != nil {
+		t.Errorf("Expected matching key pair to be valid, got: %v", err)
+	}
+	if err := ValidateSigningKeyPair(private1, public2); err == nil {
+		t.Error("Expected error for keys of different pairs")
+	}
+	if err := ValidateSigningKeyPair(public1, public1); err == nil {
+		t.Error("Expected error when the private key is not a private key")
+	}
+	if err := ValidateSigningKeyPair(filepath.Join(tmpDir, "missing"), public1); err == nil {
+		t.Error("Expected error for a missing private key")
+	}
+}
+
+func TestCheckAWSCLI(t *testing.T) {
+	cfg := &Config{SkipPreflight: true}
+	if features := AWSCLIFeatures(cfg); len(features) != 0 {
+		t.Errorf("Expected no feature needing the aws CLI, got %v", features)
+	}
+	cfg.Tags = map[string]string{"team": "ocp"}
+	cfg.PermissionsBoundaryArn = "arn:aws:iam::123456789012:policy/boundary"
+	if features := AWSCLIFeatures(cfg); strings.Join(features, ",") != "tags,permissionsBoundaryArn" {
+		t.Errorf("Unexpected features %v", features)
+	}
+	// The preflight checks and the IAM simulation are on by default
+	cfg.SkipPreflight = false
+	if features := AWSCLIFeatures(cfg); strings.Join(features, ",") != "tags,permissionsBoundaryArn,preflight,IAM permission simulation" {
+		t.Errorf("Unexpected features %v", features)
+	}
+
+	// Without any feature, the aws CLI is not needed
+	t.Setenv("PATH", t.TempDir())
+	if err := CheckAWSCLI(); err != nil {
+		t.Errorf("Expected no error without features, got %v", err)
+	}
+	if err := CheckAWSCLI("tags"); err == nil || !strings.Contains(err.Error(), "tags") {
+		t.Errorf("Expected an error naming the feature, got %v", err)
+	}
+}
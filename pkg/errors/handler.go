@@ -10,18 +10,39 @@ type StepError struct {
 	Error    error
 }
 
+// Section is a titled list of extra details shown in the summary
+type Section struct {
+	Title string
+	Lines []string
+}
+
 type Summary struct {
 	Successful []string
 	Failed     []StepError
+	Sections   []Section
+	// PausedAt is the step the installation paused before, if any
+	PausedAt string
 }
 
 func NewSummary() *Summary {
 	return &Summary{
 		Successful: []string{},
 		Failed:     []StepError{},
+		Sections:   []Section{},
 	}
 }
 
+// AddDetail appends a line to the section with the given title, creating it if needed
+func (s *Summary) AddDetail(title string, line string) {
+	for i := range s.Sections {
+		if s.Sections[i].Title == title {
+			s.Sections[i].Lines = append(s.Sections[i].Lines, line)
+			return
+		}
+	}
+	s.Sections = append(s.Sections, Section{Title: title, Lines: []string{line}})
+}
+
 func (s *Summary) AddSuccess(stepName string) {
 	s.Successful = append(s.Successful, stepName)
 }
@@ -33,6 +54,16 @@ func (s *Summary) AddError(stepName string, err error) {
 	})
 }
 
+// Pause records that the installation stopped before a step on purpose
+func (s *Summary) Pause(stepName string) {
+	s.PausedAt = stepName
+}
+
+// IsPaused reports whether the installation paused before completing
+func (s *Summary) IsPaused() bool {
+	return s.PausedAt != ""
+}
+
 func (s *Summary) HasErrors() bool {
 	return len(s.Failed) > 0
 }
@@ -58,8 +89,18 @@ func (s *Summary) String() string {
 		sb.WriteString("\n")
 	}
 
+	for _, section := range s.Sections {
+		sb.WriteString(fmt.Sprintf("%s:\n", section.Title))
+		for _, line := range section.Lines {
+			sb.WriteString(fmt.Sprintf("  - %s\n", line))
+		}
+		sb.WriteString("\n")
+	}
+
 	if s.HasErrors() {
 		sb.WriteString("Overall status: PARTIAL SUCCESS (some steps failed)\n")
+	} else if s.IsPaused() {
+		sb.WriteString(fmt.Sprintf("Overall status: PAUSED before %s\n", s.PausedAt))
 	} else if len(s.Successful) > 0 {
 		sb.WriteString("Overall status: SUCCESS\n")
 	} else {
@@ -50,6 +50,19 @@ func TestErrorSummaryString(t *testing.T) {
 	}
 }
 
+func TestPausedSummary(t *testing.T) {
+	summary := NewSummary()
+	summary.AddSuccess("Step 6")
+	summary.Pause("[Step 7] Create AWS resources")
+
+	if !summary.IsPaused() || summary.HasErrors() {
+		t.Error("Summary should be paused without errors")
+	}
+	if output := summary.String(); !strings.Contains(output, "Overall status: PAUSED before [Step 7] Create AWS resources") {
+		t.Errorf("Summary should report the pause, got:\n%s", output)
+	}
+}
+
 func TestEmptySummary(t *testing.T) {
 	summary := NewSummary()
 
@@ -61,3 +74,21 @@ func TestEmptySummary(t *testing.T) {
 		t.Error("Empty summary should have no successful steps")
 	}
 }
+
+func TestSummaryDetails(t *testing.T) {
+	summary := NewSummary()
+	summary.AddDetail("Hardening", "fips: true (Step 5)")
+	summary.AddDetail("Hardening", "etcd encryption (Step 6)")
+
+	if len(summary.Sections) != 1 {
+		t.Fatalf("Expected 1 section, got %d", len(summary.Sections))
+	}
+	if len(summary.Sections[0].Lines) != 2 {
+		t.Errorf("Expected 2 lines in section, got %d", len(summary.Sections[0].Lines))
+	}
+
+	output := summary.String()
+	if !strings.Contains(output, "Hardening:") || !strings.Contains(output, "etcd encryption (Step 6)") {
+		t.Errorf("Summary should contain the section and its lines, got:\n%s", output)
+	}
+}
@@ -0,0 +1,159 @@
+package iac
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type obj = map[string]interface{}
+
+// managedCachingOptimized is the ID of the CloudFront managed CachingOptimized policy
+const managedCachingOptimized = "658327ea-f89d-4fab-a63d-7e88639e58f6"
+
+func renderCloudFormation(m *model) (string, error) {
+	resources := obj{}
+
+	bucket := obj{"BucketName": cfnString(m.Bucket)}
+	var reader interface{} = "*"
+	if m.PrivateBucket {
+		bucket["PublicAccessBlockConfiguration"] = obj{
+			"BlockPublicAcls":       true,
+			"IgnorePublicAcls":      true,
+			"BlockPublicPolicy":     true,
+			"RestrictPublicBuckets": true,
+		}
+		reader = obj{"CanonicalUser": obj{"Fn::GetAtt": []string{"OIDCOriginAccessIdentity", "S3CanonicalUserId"}}}
+		resources["OIDCOriginAccessIdentity"] = obj{
+			"Type": "AWS::CloudFront::CloudFrontOriginAccessIdentity",
+			"Properties": obj{
+				"CloudFrontOriginAccessIdentityConfig": obj{"Comment": cfnString("OIDC discovery for " + tokenCluster)},
+			},
+		}
+		resources["OIDCDistribution"] = obj{
+			"Type": "AWS::CloudFront::Distribution",
+			"Properties": obj{
+				"DistributionConfig": obj{
+					"Enabled": true,
+					"Origins": []obj{{
+						"Id":         "oidc-bucket",
+						"DomainName": obj{"Fn::GetAtt": []string{"OIDCBucket", "RegionalDomainName"}},
+						"S3OriginConfig": obj{
+							"OriginAccessIdentity": obj{"Fn::Sub": "origin-access-identity/cloudfront/${OIDCOriginAccessIdentity}"},
+						},
+					}},
+					"DefaultCacheBehavior": obj{
+						"TargetOriginId":       "oidc-bucket",
+						"ViewerProtocolPolicy": "https-only",
+						"CachePolicyId":        managedCachingOptimized,
+					},
+				},
+			},
+		}
+	} else {
+		// The discovery documents must be publicly readable
+		bucket["PublicAccessBlockConfiguration"] = obj{
+			"BlockPublicAcls":       true,
+			"IgnorePublicAcls":      true,
+			"BlockPublicPolicy":     false,
+			"RestrictPublicBuckets": false,
+		}
+	}
+	resources["OIDCBucket"] = obj{"Type": "AWS::S3::Bucket", "Properties": bucket}
+	resources["OIDCBucketPolicy"] = obj{
+		"Type": "AWS::S3::BucketPolicy",
+		"Properties": obj{
+			"Bucket": obj{"Ref": "OIDCBucket"},
+			"PolicyDocument": obj{
+				"Version": "2012-10-17",
+				"Statement": []obj{{
+					"Effect":    "Allow",
+					"Principal": reader,
+					"Action":    "s3:GetObject",
+					"Resource":  obj{"Fn::Sub": "arn:aws:s3:::${OIDCBucket}/*"},
+				}},
+			},
+		},
+	}
+
+	resources["OIDCProvider"] = obj{
+		"Type": "AWS::IAM::OIDCProvider",
+		"Properties": obj{
+			"Url":            cfnString(m.IssuerURL),
+			"ClientIdList":   m.ClientIDs,
+			"ThumbprintList": m.Thumbprints,
+		},
+	}
+
+	outputs := obj{
+		"IssuerURL": obj{"Value": cfnString(m.IssuerURL)},
+		"Bucket":    obj{"Value": obj{"Ref": "OIDCBucket"}},
+	}
+
+	for _, r := range m.Roles {
+		var policies []obj
+		for _, p := range r.Policies {
+			policies = append(policies, obj{
+				"PolicyName":     cfnString(p.Name),
+				"PolicyDocument": cfnString(p.Document),
+			})
+		}
+		properties := obj{
+			"RoleName":                 cfnString(r.Name),
+			"AssumeRolePolicyDocument": cfnString(r.TrustPolicy),
+		}
+		if r.Description != "" {
+			properties["Description"] = cfnString(r.Description)
+		}
+		if len(policies) > 0 {
+			properties["Policies"] = policies
+		}
+
+		logicalID := "Role" + camelCase(r.ID)
+		resources[logicalID] = obj{
+			"Type":       "AWS::IAM::Role",
+			"DependsOn":  "OIDCProvider",
+			"Properties": properties,
+		}
+		outputs[logicalID+"Arn"] = obj{"Value": obj{"Fn::GetAtt": []string{logicalID, "Arn"}}}
+	}
+
+	template := obj{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "OIDC provider and IAM roles for OpenShift STS, generated from a ccoctl dry run",
+		"Parameters": obj{
+			"ClusterName": obj{
+				"Type":        "String",
+				"Default":     m.ClusterName,
+				"Description": "Name used as prefix for the OIDC bucket and the IAM roles",
+			},
+			"Region": obj{
+				"Type":        "String",
+				"Default":     m.Region,
+				"Description": "AWS region of the cluster, must match the region of the stack",
+			},
+		},
+		"Resources": resources,
+		"Outputs":   outputs,
+	}
+
+	out, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// cfnString returns s as is, or as an Fn::Sub when it contains placeholders
+func cfnString(s string) interface{} {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "${", "${!")
+	s = strings.NewReplacer(
+		tokenCluster, "${ClusterName}",
+		tokenRegion, "${Region}",
+		tokenAccount, "${AWS::AccountId}",
+		tokenIssuer, "${OIDCDistribution.DomainName}",
+	).Replace(s)
+	return obj{"Fn::Sub": s}
+}
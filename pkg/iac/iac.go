@@ -0,0 +1,212 @@
+// Package iac converts the resources of a ccoctl dry run into
+// infrastructure-as-code templates
+package iac
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// Supported template formats
+const (
+	FormatCloudFormation = "cloudformation"
+	FormatTerraform      = "terraform"
+)
+
+// Placeholders for the values that become template parameters or references
+const (
+	tokenCluster = "{{cluster}}"
+	tokenRegion  = "{{region}}"
+	tokenAccount = "{{account}}"
+	tokenIssuer  = "{{issuer}}"
+)
+
+// arnPattern splits an ARN into partition, service, region, account and resource
+var arnPattern = regexp.MustCompile(`arn:(aws[a-z-]*):([a-z0-9-]+):([a-z0-9-]*):(\d{12})?:([^"\s]+)`)
+
+// Options are the values the dry run was generated with
+type Options struct {
+	ClusterName   string
+	Region        string
+	PrivateBucket bool
+}
+
+// model is the dry run output with cluster, region, account and issuer
+// replaced by placeholders
+type model struct {
+	Options
+	Bucket      string
+	IssuerURL   string
+	ClientIDs   []string
+	Thumbprints []string
+	Roles       []role
+	Discovery   map[string]string
+}
+
+type role struct {
+	// ID is the role name without the cluster prefix, used for resource names
+	ID          string
+	Name        string
+	Description string
+	TrustPolicy string
+	Policies    []util.DryRunPolicy
+}
+
+// Render converts the dry run output into a template of the given format
+func Render(format string, out *util.DryRunOutput, opts Options) (string, error) {
+	m, err := newModel(out, opts)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case FormatCloudFormation:
+		return renderCloudFormation(m)
+	case FormatTerraform:
+		return renderTerraform(m), nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected %q or %q)", format, FormatCloudFormation, FormatTerraform)
+}
+
+func newModel(out *util.DryRunOutput, opts Options) (*model, error) {
+	if opts.ClusterName == "" || opts.Region == "" {
+		return nil, fmt.Errorf("cluster name and region are required")
+	}
+	if out.IdentityProvider == nil {
+		return nil, fmt.Errorf("ccoctl dry run did not describe an OIDC identity provider")
+	}
+	if len(out.Roles) == 0 {
+		return nil, fmt.Errorf("ccoctl dry run did not describe any IAM role")
+	}
+
+	bucket := out.Bucket
+	if bucket == "" {
+		bucket = util.OIDCBucketName(opts.ClusterName)
+	}
+	p := newParameterizer(opts, bucket, strings.TrimPrefix(out.IdentityProvider.URL, "https://"))
+	parameterize := p.document
+
+	m := &model{
+		Options:     opts,
+		Bucket:      p.name(bucket),
+		IssuerURL:   "https://" + p.issuerHost,
+		ClientIDs:   out.IdentityProvider.ClientIDs,
+		Thumbprints: out.IdentityProvider.Thumbprints,
+		Discovery:   map[string]string{},
+	}
+	for key, content := range out.Discovery {
+		m.Discovery[key] = parameterize(prettyJSON(content))
+	}
+	for _, r := range out.Roles {
+		converted := role{
+			ID:          strings.TrimPrefix(r.Name, opts.ClusterName+"-"),
+			Name:        p.name(r.Name),
+			Description: parameterize(r.Description),
+			TrustPolicy: parameterize(prettyJSON(r.TrustPolicy)),
+		}
+		for _, policy := range r.Policies {
+			converted.Policies = append(converted.Policies, util.DryRunPolicy{
+				Name:     p.name(policy.Name),
+				Document: parameterize(prettyJSON(policy.Document)),
+			})
+		}
+		m.Roles = append(m.Roles, converted)
+	}
+	return m, nil
+}
+
+// parameterizer replaces cluster, region, account and issuer with their
+// placeholders only where ccoctl puts them, since the cluster name or the
+// region may also appear elsewhere, e.g. in openshift-* service accounts
+type parameterizer struct {
+	opts Options
+	// issuerHost is the issuer host with placeholders, rawIssuerHost the original
+	issuerHost    string
+	rawIssuerHost string
+}
+
+func newParameterizer(opts Options, bucket, issuerHost string) *parameterizer {
+	p := &parameterizer{opts: opts, issuerHost: issuerHost, rawIssuerHost: issuerHost}
+	switch {
+	case opts.PrivateBucket:
+		// Behind CloudFront the issuer host is only known once the distribution exists
+		p.issuerHost = tokenIssuer
+	case issuerHost == fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, opts.Region):
+		p.issuerHost = fmt.Sprintf("%s.s3.%s.amazonaws.com", p.name(bucket), tokenRegion)
+	}
+	return p
+}
+
+// name parameterizes the <cluster>- prefix of a role, policy or bucket name
+func (p *parameterizer) name(name string) string {
+	if rest, ok := strings.CutPrefix(name, p.opts.ClusterName+"-"); ok {
+		return tokenCluster + "-" + rest
+	}
+	return name
+}
+
+// document parameterizes the issuer host and the ARNs of a policy, trust or
+// discovery document
+func (p *parameterizer) document(s string) string {
+	if p.rawIssuerHost != "" {
+		s = strings.ReplaceAll(s, p.rawIssuerHost, p.issuerHost)
+	}
+	return arnPattern.ReplaceAllStringFunc(s, func(arn string) string {
+		parts := arnPattern.FindStringSubmatch(arn)
+		partition, service, region, account, resource := parts[1], parts[2], parts[3], parts[4], parts[5]
+		if region == p.opts.Region {
+			region = tokenRegion
+		}
+		if account != "" {
+			account = tokenAccount
+		}
+		// Role and bucket names; the provider was handled with the issuer host
+		if rest, ok := strings.CutPrefix(resource, "role/"); ok {
+			resource = "role/" + p.name(rest)
+		} else if service == "s3" {
+			resource = p.name(resource)
+		}
+		return fmt.Sprintf("arn:%s:%s:%s:%s:%s", partition, service, region, account, resource)
+	})
+}
+
+func prettyJSON(document string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(document), "", "  "); err != nil {
+		return document
+	}
+	return out.String()
+}
+
+// camelCase turns a role ID like openshift-ingress-operator into OpenshiftIngressOperator
+func camelCase(id string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// snakeCase turns a role ID like openshift-ingress-operator into openshift_ingress_operator
+func snakeCase(id string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, id)
+}
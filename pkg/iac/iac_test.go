@@ -0,0 +1,149 @@
+package iac
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func testDryRunOutput() *util.DryRunOutput {
+	return &util.DryRunOutput{
+		Bucket: "test-cluster-oidc",
+		IdentityProvider: &util.DryRunIdentityProvider{
+			URL:         "https://test-cluster-oidc.s3.us-east-2.amazonaws.com",
+			ClientIDs:   []string{"openshift", "sts.amazonaws.com"},
+			Thumbprints: []string{"a9d53002e97e00e043244f3d170d6f4c414104fd"},
+		},
+		Roles: []util.DryRunRole{{
+			Name:        "test-cluster-openshift-ingress-operator-cloud-credentials",
+			TrustPolicy: `{"Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com"},"Action":"sts:AssumeRoleWithWebIdentity"}]}`,
+			Policies: []util.DryRunPolicy{{
+				Name:     "test-cluster-openshift-ingress-operator-cloud-credentials",
+				Document: `{"Statement":[{"Effect":"Allow","Action":["route53:ListHostedZones"],"Resource":"arn:aws:s3:::test-cluster-registry/${aws:username}"}]}`,
+			}},
+		}},
+		Discovery: map[string]string{"keys.json": `{"keys":[]}`},
+	}
+}
+
+var testOptions = Options{ClusterName: "test-cluster", Region: "us-east-2"}
+
+func TestRenderCloudFormation(t *testing.T) {
+	out, err := Render(FormatCloudFormation, testDryRunOutput(), testOptions)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	var template map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &template); err != nil {
+		t.Fatalf("Template is not valid JSON: %v", err)
+	}
+	resources := template["Resources"].(map[string]interface{})
+	for _, id := range []string{"OIDCBucket", "OIDCBucketPolicy", "OIDCProvider", "RoleOpenshiftIngressOperatorCloudCredentials"} {
+		if _, ok := resources[id]; !ok {
+			t.Errorf("Expected resource %s", id)
+		}
+	}
+	if _, ok := resources["OIDCDistribution"]; ok {
+		t.Error("CloudFront distribution is only needed for a private bucket")
+	}
+
+	for _, want := range []string{
+		`"Fn::Sub": "${ClusterName}-openshift-ingress-operator-cloud-credentials"`,
+		`"Fn::Sub": "https://${ClusterName}-oidc.s3.${Region}.amazonaws.com"`,
+		`arn:aws:iam::${AWS::AccountId}:oidc-provider/${ClusterName}-oidc.s3.${Region}.amazonaws.com`,
+		`${!aws:username}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected template to contain %s", want)
+		}
+	}
+	if strings.Contains(out, "123456789012") {
+		t.Error("Account ID should be parameterized")
+	}
+}
+
+func TestRenderCloudFormationPrivateBucket(t *testing.T) {
+	opts := testOptions
+	opts.PrivateBucket = true
+	out, err := Render(FormatCloudFormation, testDryRunOutput(), opts)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out, "AWS::CloudFront::Distribution") || !strings.Contains(out, "https://${OIDCDistribution.DomainName}") {
+		t.Errorf("Expected the issuer to be served by CloudFront:\n%s", out)
+	}
+}
+
+func TestRenderTerraform(t *testing.T) {
+	out, err := Render(FormatTerraform, testDryRunOutput(), testOptions)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		`variable "cluster_name"`,
+		`default     = "test-cluster"`,
+		`bucket = "${var.cluster_name}-oidc"`,
+		`resource "aws_iam_role" "openshift_ingress_operator_cloud_credentials"`,
+		`arn:aws:iam::${data.aws_caller_identity.current.account_id}:oidc-provider/${var.cluster_name}-oidc.s3.${var.region}.amazonaws.com`,
+		`resource "aws_iam_role_policy" "openshift_ingress_operator_cloud_credentials"`,
+		`$${aws:username}`,
+		`key          = "keys.json"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected template to contain %s", want)
+		}
+	}
+}
+
+func TestRenderClusterNameInSubjects(t *testing.T) {
+	// A cluster named like the openshift-* namespaces of the service accounts
+	issuer := "openshift-oidc.s3.us-east-2.amazonaws.com"
+	out := &util.DryRunOutput{
+		Bucket:           "openshift-oidc",
+		IdentityProvider: &util.DryRunIdentityProvider{URL: "https://" + issuer},
+		Roles: []util.DryRunRole{{
+			Name: "openshift-openshift-ingress-operator-cloud-credentials",
+			TrustPolicy: `{"Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/` + issuer + `"},` +
+				`"Action":"sts:AssumeRoleWithWebIdentity","Condition":{"StringEquals":{"` + issuer + `:sub":["system:serviceaccount:openshift-ingress-operator:cloud-credentials"]}}}]}`,
+			Policies: []util.DryRunPolicy{{
+				Name:     "openshift-openshift-ingress-operator-cloud-credentials",
+				Document: `{"Statement":[{"Effect":"Allow","Action":["elasticloadbalancing:DescribeLoadBalancers"],"Resource":"arn:aws:ec2:us-east-2:123456789012:instance/*"}]}`,
+			}},
+		}},
+	}
+
+	template, err := Render(FormatTerraform, out, Options{ClusterName: "openshift", Region: "us-east-2"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		`resource "aws_iam_role" "openshift_ingress_operator_cloud_credentials"`,
+		`"${var.cluster_name}-openshift-ingress-operator-cloud-credentials"`,
+		`system:serviceaccount:openshift-ingress-operator:cloud-credentials`,
+		`oidc-provider/${var.cluster_name}-oidc.s3.${var.region}.amazonaws.com`,
+		`${var.cluster_name}-oidc.s3.${var.region}.amazonaws.com:sub`,
+		`arn:aws:ec2:${var.region}:${data.aws_caller_identity.current.account_id}:instance/*`,
+	} {
+		if !strings.Contains(template, want) {
+			t.Errorf("Expected template to contain %s:\n%s", want, template)
+		}
+	}
+	if strings.Contains(template, "system:serviceaccount:${var.cluster_name}") {
+		t.Error("Service-account subjects must not be parameterized")
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render("pulumi", testDryRunOutput(), testOptions); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := Render(FormatTerraform, &util.DryRunOutput{}, testOptions); err == nil {
+		t.Error("Expected error without an identity provider")
+	}
+	if _, err := Render(FormatTerraform, testDryRunOutput(), Options{}); err == nil {
+		t.Error("Expected error without cluster name and region")
+	}
+}
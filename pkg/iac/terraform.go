@@ -0,0 +1,137 @@
+package iac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func renderTerraform(m *model) string {
+	var sb strings.Builder
+	w := func(format string, args ...interface{}) {
+		sb.WriteString(fmt.Sprintf(format, args...))
+	}
+
+	w("# OIDC provider and IAM roles for OpenShift STS, generated from a ccoctl dry run\n\n")
+	w("variable \"cluster_name\" {\n  description = \"Name used as prefix for the OIDC bucket and the IAM roles\"\n  type        = string\n  default     = %s\n}\n\n", hclQuote(m.ClusterName))
+	w("variable \"region\" {\n  description = \"AWS region of the cluster\"\n  type        = string\n  default     = %s\n}\n\n", hclQuote(m.Region))
+	w("provider \"aws\" {\n  region = var.region\n}\n\n")
+	w("data \"aws_caller_identity\" \"current\" {}\n\n")
+
+	w("resource \"aws_s3_bucket\" \"oidc\" {\n  bucket = %s\n}\n\n", hclString(m.Bucket))
+
+	if m.PrivateBucket {
+		w("resource \"aws_s3_bucket_public_access_block\" \"oidc\" {\n  bucket                  = aws_s3_bucket.oidc.id\n  block_public_acls       = true\n  ignore_public_acls      = true\n  block_public_policy     = true\n  restrict_public_buckets = true\n}\n\n")
+		w("resource \"aws_cloudfront_origin_access_identity\" \"oidc\" {\n  comment = %s\n}\n\n", hclString("OIDC discovery for "+tokenCluster))
+		w(`resource "aws_cloudfront_distribution" "oidc" {
+  enabled = true
+
+  origin {
+    origin_id   = "oidc-bucket"
+    domain_name = aws_s3_bucket.oidc.bucket_regional_domain_name
+
+    s3_origin_config {
+      origin_access_identity = aws_cloudfront_origin_access_identity.oidc.cloudfront_access_identity_path
+    }
+  }
+
+  default_cache_behavior {
+    target_origin_id       = "oidc-bucket"
+    viewer_protocol_policy = "https-only"
+    allowed_methods        = ["GET", "HEAD"]
+    cached_methods         = ["GET", "HEAD"]
+    cache_policy_id        = %s
+  }
+
+  restrictions {
+    geo_restriction {
+      restriction_type = "none"
+    }
+  }
+
+  viewer_certificate {
+    cloudfront_default_certificate = true
+  }
+}
+
+`, hclQuote(managedCachingOptimized))
+		w("resource \"aws_s3_bucket_policy\" \"oidc\" {\n  bucket = aws_s3_bucket.oidc.id\n  policy = jsonencode({\n    Version = \"2012-10-17\"\n    Statement = [{\n      Effect    = \"Allow\"\n      Principal = { AWS = aws_cloudfront_origin_access_identity.oidc.iam_arn }\n      Action    = \"s3:GetObject\"\n      Resource  = \"${aws_s3_bucket.oidc.arn}/*\"\n    }]\n  })\n}\n\n")
+	} else {
+		w("# The discovery documents must be publicly readable\n")
+		w("resource \"aws_s3_bucket_public_access_block\" \"oidc\" {\n  bucket                  = aws_s3_bucket.oidc.id\n  block_public_acls       = true\n  ignore_public_acls      = true\n  block_public_policy     = false\n  restrict_public_buckets = false\n}\n\n")
+		w("resource \"aws_s3_bucket_policy\" \"oidc\" {\n  bucket     = aws_s3_bucket.oidc.id\n  depends_on = [aws_s3_bucket_public_access_block.oidc]\n  policy = jsonencode({\n    Version = \"2012-10-17\"\n    Statement = [{\n      Effect    = \"Allow\"\n      Principal = \"*\"\n      Action    = \"s3:GetObject\"\n      Resource  = \"${aws_s3_bucket.oidc.arn}/*\"\n    }]\n  })\n}\n\n")
+	}
+
+	keys := make([]string, 0, len(m.Discovery))
+	for key := range m.Discovery {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w("resource \"aws_s3_object\" %s {\n  bucket       = aws_s3_bucket.oidc.id\n  key          = %s\n  content_type = \"application/json\"\n  content      = %s\n}\n\n",
+			hclQuote(snakeCase(strings.TrimPrefix(key, ".well-known/"))), hclQuote(key), hclHeredoc(m.Discovery[key]))
+	}
+
+	w("resource \"aws_iam_openid_connect_provider\" \"oidc\" {\n  url             = %s\n  client_id_list  = %s\n  thumbprint_list = %s\n}\n\n",
+		hclString(m.IssuerURL), hclList(m.ClientIDs), hclList(m.Thumbprints))
+
+	for _, r := range m.Roles {
+		name := snakeCase(r.ID)
+		w("resource \"aws_iam_role\" %s {\n  name               = %s\n", hclQuote(name), hclString(r.Name))
+		if r.Description != "" {
+			w("  description        = %s\n", hclString(r.Description))
+		}
+		w("  assume_role_policy = %s\n  depends_on         = [aws_iam_openid_connect_provider.oidc]\n}\n\n", hclHeredoc(r.TrustPolicy))
+		for i, p := range r.Policies {
+			policyName := name
+			if i > 0 {
+				policyName = fmt.Sprintf("%s_%d", name, i)
+			}
+			w("resource \"aws_iam_role_policy\" %s {\n  name   = %s\n  role   = aws_iam_role.%s.id\n  policy = %s\n}\n\n",
+				hclQuote(policyName), hclString(p.Name), name, hclHeredoc(p.Document))
+		}
+	}
+
+	w("output \"issuer_url\" {\n  value = %s\n}\n", hclString(m.IssuerURL))
+	for _, r := range m.Roles {
+		w("\noutput %s {\n  value = aws_iam_role.%s.arn\n}\n", hclQuote(snakeCase(r.ID)+"_arn"), snakeCase(r.ID))
+	}
+
+	return sb.String()
+}
+
+// hclTemplate escapes Terraform interpolation in s and turns placeholders into references
+func hclTemplate(s string) string {
+	s = strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+	return strings.NewReplacer(
+		tokenCluster, "${var.cluster_name}",
+		tokenRegion, "${var.region}",
+		tokenAccount, "${data.aws_caller_identity.current.account_id}",
+		tokenIssuer, "${aws_cloudfront_distribution.oidc.domain_name}",
+	).Replace(s)
+}
+
+// hclQuote quotes a literal string
+func hclQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "${", "$${", "%{", "%%{").Replace(s)
+	return `"` + s + `"`
+}
+
+// hclString quotes a string that may contain placeholders
+func hclString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + hclTemplate(s) + `"`
+}
+
+// hclHeredoc renders a multi-line document that may contain placeholders
+func hclHeredoc(s string) string {
+	return "<<-EOT\n" + hclTemplate(strings.TrimRight(s, "\n")) + "\nEOT"
+}
+
+func hclList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclQuote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,62 @@
+package steps
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const boundaryDetailTitle = "Permissions boundary"
+
+// attachPermissionsBoundary attaches the configured permissions boundary to
+// every role created by ccoctl and verifies it with aws iam get-role
+func (s *Step7CreateAWSResources) attachPermissionsBoundary(state *ccoctlState) error {
+	if s.cfg.PermissionsBoundaryArn == "" {
+		return nil
+	}
+
+	resources, err := s.ccoctlResources(state)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, resource := range resources {
+		if resource.Kind != resourceIAMRole {
+			continue
+		}
+		if err := s.attachRoleBoundary(resource.ID); err != nil {
+			s.log.Debug(err.Error())
+			failed = append(failed, resource.ID)
+			s.addDetail(boundaryDetailTitle, fmt.Sprintf("✗ %s: %v", resource.ID, err))
+			continue
+		}
+		s.addDetail(boundaryDetailTitle, fmt.Sprintf("✓ %s", resource.ID))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("permissions boundary %s missing on roles: %s", s.cfg.PermissionsBoundaryArn, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (s *Step7CreateAWSResources) attachRoleBoundary(roleName string) error {
+	if _, err := util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "put-role-permissions-boundary",
+		"--role-name", roleName, "--permissions-boundary", s.cfg.PermissionsBoundaryArn); err != nil {
+		return err
+	}
+
+	output, err := util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "get-role", "--role-name", roleName)
+	if err != nil {
+		return fmt.Errorf("failed to verify permissions boundary: %w", err)
+	}
+	boundary, err := util.ParseRolePermissionsBoundary(output)
+	if err != nil {
+		return err
+	}
+	if boundary != s.cfg.PermissionsBoundaryArn {
+		return fmt.Errorf("role has permissions boundary %q after attaching", boundary)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package steps
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const testBoundaryARN = "arn:aws:iam::123456789012:policy/org-boundary"
+
+func TestStep7AttachesPermissionsBoundary(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:           "quay.io/test:4.12.0-x86_64",
+		ClusterName:            "test-cluster",
+		AwsRegion:              "us-east-2",
+		OutputDir:              "_output",
+		PermissionsBoundaryArn: testBoundaryARN,
+	}
+	writeCcoctlOutput(t, cfg.OutputDir)
+
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	getRole := "aws iam get-role --role-name test-cluster-ingress --output json"
+	executor.SetOutput(getRole, `{"Role":{"RoleName":"test-cluster-ingress"}}`)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+
+	// The boundary is not visible on the role: the step fails
+	err = step.Execute()
+	if err == nil || !strings.Contains(err.Error(), "test-cluster-ingress") {
+		t.Errorf("Expected error naming the role without boundary, got: %v", err)
+	}
+	if !executor.WasExecuted("aws iam put-role-permissions-boundary --role-name test-cluster-ingress --permissions-boundary " + testBoundaryARN + " --output json") {
+		t.Errorf("Expected the boundary to be attached, got %v", executor.Commands)
+	}
+	if CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should not be complete without the boundary")
+	}
+
+	// The re-run attaches and verifies the boundary
+	executor.SetOutput(getRole, `{"Role":{"RoleName":"test-cluster-ingress","PermissionsBoundary":{"PermissionsBoundaryType":"Policy","PermissionsBoundaryArn":"`+testBoundaryARN+`"}}}`)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should be complete")
+	}
+}
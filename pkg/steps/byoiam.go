@@ -0,0 +1,102 @@
+package steps
+
+import (
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// Files ccoctl writes to the output directory, rendered here for bring-your-own IAM
+const (
+	authenticationConfigManifest = "cluster-authentication-02-config.yaml"
+	boundSigningKeyFile          = "bound-service-account-signing-key.key"
+	webIdentityTokenFile         = "/var/run/secrets/openshift/serviceaccount/token"
+)
+
+// renderBYOIAM writes the manifests and signing key that ccoctl would
+// produce, using the IAM roles and OIDC issuer provided in the config
+func renderBYOIAM(byo *config.BYOIAMConfig, credReqsDir, outputDir string) error {
+	requests, err := util.ReadCredentialsRequests(credReqsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials requests: %w", err)
+	}
+	if len(requests) == 0 {
+		return fmt.Errorf("no CredentialsRequest found in %s", credReqsDir)
+	}
+
+	var unmapped []string
+	for _, request := range requests {
+		if byo.Roles[request.Metadata.Name] == "" {
+			unmapped = append(unmapped, request.Metadata.Name)
+		}
+	}
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		return fmt.Errorf("no role in byoIAM.roles for CredentialsRequest: %s", strings.Join(unmapped, ", "))
+	}
+
+	keyContent, err := os.ReadFile(byo.SigningKey)
+	if err != nil {
+		return fmt.Errorf("failed to read signing key: %w", err)
+	}
+	if block, _ := pem.Decode(keyContent); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return fmt.Errorf("signing key %s is not a PEM private key", byo.SigningKey)
+	}
+
+	manifestsDir := filepath.Join(outputDir, "manifests")
+	tlsDir := filepath.Join(outputDir, "tls")
+	for _, dir := range []string{manifestsDir, tlsDir} {
+		if err := util.EnsureDir(dir); err != nil {
+			return err
+		}
+	}
+
+	for _, request := range requests {
+		ref := request.Spec.SecretRef
+		if ref.Name == "" || ref.Namespace == "" {
+			return fmt.Errorf("CredentialsRequest %s has no secretRef", request.Metadata.Name)
+		}
+		name := fmt.Sprintf("%s-%s-credentials.yaml", ref.Namespace, ref.Name)
+		secret := credentialsSecret(ref.Namespace, ref.Name, byo.Roles[request.Metadata.Name])
+		if err := os.WriteFile(filepath.Join(manifestsDir, name), []byte(secret), 0600); err != nil {
+			return err
+		}
+	}
+
+	authentication := fmt.Sprintf(`apiVersion: config.openshift.io/v1
+kind: Authentication
+metadata:
+  name: cluster
+spec:
+  serviceAccountIssuer: %s
+`, byo.IssuerURL)
+	if err := os.WriteFile(filepath.Join(manifestsDir, authenticationConfigManifest), []byte(authentication), 0644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(tlsDir, boundSigningKeyFile), keyContent, 0600)
+}
+
+// credentialsSecret renders the Secret that lets a component assume its role
+// through the projected service account token
+func credentialsSecret(namespace, name, roleARN string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Secret
+metadata:
+  name: %s
+  namespace: %s
+type: Opaque
+stringData:
+  credentials: |-
+    [default]
+    sts_regional_endpoints = regional
+    role_arn = %s
+    web_identity_token_file = %s
+`, name, namespace, roleARN, webIdentityTokenFile)
+}
@@ -0,0 +1,188 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// Sub-steps of Step 7: the ccoctl commands and what the installer does to
+// the resources afterwards
+const (
+	SubStepCreateKeyPair          = "create-key-pair"
+	SubStepCreateIdentityProvider = "create-identity-provider"
+	SubStepCreateIAMRoles         = "create-iam-roles"
+	SubStepPermissionsBoundary    = "attach-permissions-boundary"
+	SubStepTagResources           = "tag-resources"
+)
+
+// ccoctlSubSteps lists the Step 7 sub-steps in execution order
+var ccoctlSubSteps = []string{
+	SubStepCreateKeyPair,
+	SubStepCreateIdentityProvider,
+	SubStepCreateIAMRoles,
+	SubStepPermissionsBoundary,
+	SubStepTagResources,
+}
+
+// subStepTitle returns the sub-step name shown in the logs
+func subStepTitle(subStep string) string {
+	switch subStep {
+	case SubStepPermissionsBoundary:
+		return "IAM permissions boundary"
+	case SubStepTagResources:
+		return "AWS resource tagging"
+	}
+	return "ccoctl aws " + subStep
+}
+
+var (
+	identityProviderARNPattern = regexp.MustCompile(`arn:aws:iam::\d{12}:oidc-provider/[^\s"']+`)
+	cloudFrontIDPattern        = regexp.MustCompile(`[Cc]loud[Ff]ront[^\n]*?\b(E[A-Z0-9]{9,14})\b`)
+)
+
+// ccoctlState tracks the completed Step 7 sub-steps and the values they hand
+// over to the next ones, so that a failed run resumes where it stopped
+type ccoctlState struct {
+	Completed           []string `json:"completed"`
+	IdentityProviderARN string   `json:"identityProviderARN,omitempty"`
+	// CloudFrontDistributionID is set with a private bucket
+	CloudFrontDistributionID string `json:"cloudFrontDistributionID,omitempty"`
+}
+
+func loadCcoctlState(outputDir string) (*ccoctlState, error) {
+	state := &ccoctlState{}
+	content, err := os.ReadFile(filepath.Join(outputDir, util.CcoctlStateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ccoctl state: %w", err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to parse ccoctl state: %w", err)
+	}
+	return state, nil
+}
+
+func (st *ccoctlState) save(outputDir string) error {
+	content, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, util.CcoctlStateFile), content, 0644)
+}
+
+func (st *ccoctlState) isDone(subStep string) bool {
+	for _, done := range st.Completed {
+		if done == subStep {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *ccoctlState) markDone(subStep string) {
+	if !st.isDone(subStep) {
+		st.Completed = append(st.Completed, subStep)
+	}
+}
+
+// CcoctlIdentityProvider returns the OIDC provider recorded by a completed
+// create-identity-provider sub-step, "" before it ran
+func CcoctlIdentityProvider(outputDir string) string {
+	state, err := loadCcoctlState(outputDir)
+	if err != nil || !state.isDone(SubStepCreateIdentityProvider) {
+		return ""
+	}
+	return state.IdentityProviderARN
+}
+
+// CcoctlCompleted reports whether every Step 7 sub-step has completed
+func CcoctlCompleted(outputDir string) bool {
+	// Output of an earlier `ccoctl aws create-all` run, which is not tracked
+	if !util.FileExists(filepath.Join(outputDir, util.CcoctlStateFile)) {
+		return util.DirExistsWithFiles(filepath.Join(outputDir, "manifests")) &&
+			util.DirExistsWithFiles(filepath.Join(outputDir, "tls"))
+	}
+
+	state, err := loadCcoctlState(outputDir)
+	if err != nil {
+		return false
+	}
+	for _, subStep := range ccoctlSubSteps {
+		if !state.isDone(subStep) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIdentityProviderARN extracts the OIDC provider ARN from the output of
+// ccoctl aws create-identity-provider
+func parseIdentityProviderARN(output string) (string, error) {
+	arn := identityProviderARNPattern.FindString(output)
+	if arn == "" {
+		return "", fmt.Errorf("identity provider ARN not found in ccoctl output")
+	}
+	return arn, nil
+}
+
+// parseCloudFrontDistributionID extracts the CloudFront distribution ID from
+// the output of ccoctl aws create-identity-provider, if there is one
+func parseCloudFrontDistributionID(output string) string {
+	if match := cloudFrontIDPattern.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// CcoctlDryRun runs `ccoctl aws create-all --dry-run` into a temporary
+// directory and returns it. The directory holds a generated private key; the
+// caller must remove it.
+func CcoctlDryRun(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (string, error) {
+	base, err := newBaseStep(cfg, log, executor)
+	if err != nil {
+		return "", err
+	}
+	return base.ccoctlDryRun()
+}
+
+func (s *BaseStep) ccoctlDryRun() (string, error) {
+	if s.cfg.ClusterName == "" || s.cfg.AwsRegion == "" {
+		return "", fmt.Errorf("cluster name and AWS region are required for a ccoctl dry run")
+	}
+
+	dryRunDir, err := os.MkdirTemp("", "ccoctl-dry-run-")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{
+		"aws", "create-all",
+		"--name", s.cfg.ClusterName,
+		"--region", s.cfg.AwsRegion,
+		"--credentials-requests-dir", util.GetCredReqsPath(s.versionArch),
+		"--output-dir", dryRunDir,
+		"--dry-run",
+	}
+	if s.cfg.PrivateBucket {
+		args = append(args, "--create-private-s3-bucket")
+	}
+
+	ccoctlBin := util.GetBinaryPath(s.versionArch, "ccoctl")
+	if err := util.RunCommandWithEnv(s.executor, s.awsEnv(s.cfg.IAMProfile()), ccoctlBin, args...); err != nil {
+		os.RemoveAll(dryRunDir)
+		return "", err
+	}
+	return dryRunDir, nil
+}
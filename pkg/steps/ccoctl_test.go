@@ -0,0 +1,108 @@
+package steps
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const (
+	testProviderARN        = "arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com"
+	testCreateKeyPair      = "artifacts/4.12.0-x86_64/bin/ccoctl aws create-key-pair --output-dir _output"
+	testCreateIdentityProv = "artifacts/4.12.0-x86_64/bin/ccoctl aws create-identity-provider --name test-cluster --region us-east-2 --public-key-file _output/serviceaccount-signer.public --output-dir _output"
+	testCreateIAMRoles     = "artifacts/4.12.0-x86_64/bin/ccoctl aws create-iam-roles --name test-cluster --region us-east-2 --credentials-requests-dir artifacts/4.12.0-x86_64/credreqs --identity-provider-arn " + testProviderARN + " --output-dir _output"
+)
+
+func TestStep7RunsCcoctlSubSteps(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	executor.SetOutput(testCreateIdentityProv, "2024/01/01 Identity Provider created with ARN: "+testProviderARN+"\n")
+	executor.SetError(testCreateIAMRoles, errors.New("exit status 1"))
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+
+	// First run fails while creating the IAM roles
+	if err := step.Execute(); err == nil {
+		t.Fatal("Expected error from create-iam-roles")
+	}
+	for _, cmd := range []string{testCreateKeyPair, testCreateIdentityProv, testCreateIAMRoles} {
+		if !executor.WasExecuted(cmd) {
+			t.Errorf("Expected command to be executed: %s", cmd)
+		}
+	}
+	if CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should not be complete after a failed sub-step")
+	}
+
+	// The rerun only retries the failed sub-step, reusing the provider ARN
+	delete(executor.Errors, testCreateIAMRoles)
+	executor.Commands = nil
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if len(executor.Commands) != 1 || executor.Commands[0] != testCreateIAMRoles {
+		t.Errorf("Expected only create-iam-roles to run, got %v", executor.Commands)
+	}
+	if !CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should be complete")
+	}
+}
+
+func TestParseIdentityProviderARN(t *testing.T) {
+	arn, err := parseIdentityProviderARN("Identity Provider created with ARN: " + testProviderARN)
+	if err != nil || arn != testProviderARN {
+		t.Errorf("Expected %s, got %q (%v)", testProviderARN, arn, err)
+	}
+	if _, err := parseIdentityProviderARN("nothing here"); err == nil {
+		t.Error("Expected error when the ARN is missing")
+	}
+}
+
+func TestStep7LooksUpUnparsedProviderARN(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+	}
+	executor := util.NewMockExecutor()
+	executor.SetOutput(testCreateIdentityProv, "2024/01/01 Identity Provider created\n")
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/other-oidc.s3.us-east-2.amazonaws.com"},
+		{"Arn": "`+testProviderARN+`"}
+	]}`)
+
+	step, err := NewStep7(cfg, logger.New(logger.LevelQuiet, nil), executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !executor.WasExecuted(testCreateIAMRoles) {
+		t.Errorf("Expected create-iam-roles with the looked up ARN, got %v", executor.Commands)
+	}
+}
@@ -45,9 +45,8 @@ func (d *Detector) ShouldSkipStep(stepNum int) bool {
 		// Step 6: Create manifests
 		return util.DirExistsWithFiles("manifests")
 	case 7:
-		// Step 7: Create AWS resources
-		return util.DirExistsWithFiles("_output/manifests") &&
-			util.DirExistsWithFiles("_output/tls")
+		// Step 7: Create AWS resources (each ccoctl sub-step is also tracked on its own)
+		return CcoctlCompleted(d.cfg.OutputDir)
 	case 8:
 		// Step 8: Copy manifests
 		return util.DirExistsWithFiles("manifests")
@@ -0,0 +1,157 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings applied by the hardening profiles
+const (
+	HardeningFIPS              = "fips"
+	HardeningIMDSv2            = "imdsv2"
+	HardeningRootVolumeKMS     = "root-volume-kms"
+	HardeningEtcdEncryption    = "etcd-encryption"
+	HardeningPrivateOIDCBucket = "private-oidc-bucket"
+)
+
+// HardeningSetting describes a single change made by a hardening profile
+type HardeningSetting struct {
+	Name        string
+	Description string
+	Step        int // step that makes the change
+	MinMinor    int // first 4.y release supporting the change
+}
+
+var hardeningSettings = []HardeningSetting{
+	{HardeningIMDSv2, "require IMDSv2 on all machine pools", 5, 11},
+	{HardeningEtcdEncryption, "enable etcd encryption (aescbc)", 6, 3},
+	{HardeningPrivateOIDCBucket, "private OIDC bucket behind CloudFront", 7, 10},
+	{HardeningFIPS, "fips: true", 5, 3},
+	{HardeningRootVolumeKMS, "customer-managed KMS key for EBS root volumes", 5, 7},
+}
+
+var hardeningProfiles = map[string][]string{
+	config.HardeningBaseline: {HardeningIMDSv2, HardeningEtcdEncryption, HardeningPrivateOIDCBucket},
+	config.HardeningStrict:   {HardeningIMDSv2, HardeningEtcdEncryption, HardeningPrivateOIDCBucket, HardeningFIPS, HardeningRootVolumeKMS},
+}
+
+// HardeningPlan returns the settings of the configured hardening profile, in
+// step order, after checking that the release version supports each of them
+func HardeningPlan(cfg *config.Config) ([]HardeningSetting, error) {
+	if _, ok := hardeningProfiles[cfg.Hardening]; !ok {
+		return nil, nil
+	}
+
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		return nil, err
+	}
+	major, minor, err := util.ParseMajorMinor(versionArch)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []HardeningSetting
+	var unsupported []string
+	for _, setting := range hardeningSettings {
+		if !hardeningEnabled(cfg, setting.Name) {
+			continue
+		}
+		if major == 4 && minor < setting.MinMinor {
+			unsupported = append(unsupported, fmt.Sprintf("%s (requires 4.%d+)", setting.Name, setting.MinMinor))
+			continue
+		}
+		plan = append(plan, setting)
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("hardening profile %q is not supported by release %s: %s",
+			cfg.Hardening, versionArch, strings.Join(unsupported, ", "))
+	}
+
+	return plan, nil
+}
+
+// reportHardening adds the settings of the hardening profile that the step
+// applied to its details
+func (s *BaseStep) reportHardening(step int) {
+	for _, setting := range hardeningSettings {
+		if setting.Step == step && hardeningEnabled(s.cfg, setting.Name) {
+			s.addDetail(fmt.Sprintf("Hardening (%s)", s.cfg.Hardening), fmt.Sprintf("[Step %d] %s", step, setting.Description))
+		}
+	}
+}
+
+func hardeningEnabled(cfg *config.Config, name string) bool {
+	for _, n := range hardeningProfiles[cfg.Hardening] {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyInstallConfigHardening sets the install-config.yaml fields of the hardening profile
+func applyInstallConfigHardening(cfg *config.Config, doc map[string]interface{}) {
+	if hardeningEnabled(cfg, HardeningFIPS) {
+		doc["fips"] = true
+	}
+
+	for _, pool := range machinePools(doc) {
+		aws := childMap(childMap(pool, "platform"), "aws")
+		if hardeningEnabled(cfg, HardeningIMDSv2) {
+			childMap(aws, "metadataService")["authentication"] = "Required"
+		}
+		if hardeningEnabled(cfg, HardeningRootVolumeKMS) {
+			childMap(aws, "rootVolume")["kmsKeyARN"] = cfg.KMSKeyARN
+		}
+	}
+}
+
+const etcdEncryptionManifest = "99_openshift-apiserver-encryption.yaml"
+
+// applyEtcdEncryption turns on etcd encryption in the APIServer config. An
+// existing APIServer manifest is patched; otherwise a new one is added to openshift/.
+func applyEtcdEncryption(versionDir string) error {
+	for _, dir := range []string{"manifests", "openshift"} {
+		paths, _ := filepath.Glob(filepath.Join(versionDir, dir, "*.yaml"))
+		for _, path := range paths {
+			if filepath.Base(path) == etcdEncryptionManifest {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			var doc map[string]interface{}
+			if err := yaml.Unmarshal(content, &doc); err != nil || doc["kind"] != "APIServer" {
+				continue
+			}
+			childMap(childMap(doc, "spec"), "encryption")["type"] = "aescbc"
+			out, err := yaml.Marshal(doc)
+			if err != nil {
+				return fmt.Errorf("failed to serialize %s: %w", path, err)
+			}
+			return os.WriteFile(path, out, 0644)
+		}
+	}
+
+	manifest := `apiVersion: config.openshift.io/v1
+kind: APIServer
+metadata:
+  name: cluster
+spec:
+  encryption:
+    type: aescbc
+`
+	openshiftDir := filepath.Join(versionDir, "openshift")
+	if err := util.EnsureDir(openshiftDir); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(openshiftDir, etcdEncryptionManifest), []byte(manifest), 0644)
+}
@@ -0,0 +1,135 @@
+package steps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestHardeningPlan(t *testing.T) {
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Hardening:    config.HardeningStrict,
+		KMSKeyARN:    "arn:aws:kms:us-east-2:123456789012:key/abc",
+	}
+
+	plan, err := HardeningPlan(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plan) != 5 {
+		t.Errorf("Expected 5 settings for strict profile, got %d", len(plan))
+	}
+
+	cfg.Hardening = config.HardeningBaseline
+	plan, err = HardeningPlan(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, setting := range plan {
+		if setting.Name == HardeningFIPS {
+			t.Error("Baseline profile should not enable FIPS")
+		}
+	}
+
+	// IMDSv2 is not configurable before 4.11
+	cfg.ReleaseImage = "quay.io/test:4.10.3-x86_64"
+	if _, err := HardeningPlan(cfg); err == nil {
+		t.Error("Expected error for a release that does not support the profile")
+	}
+
+	cfg.Hardening = ""
+	plan, err = HardeningPlan(cfg)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("Expected empty plan without a profile, got %v, %v", plan, err)
+	}
+}
+
+func TestStep5AppliesHardening(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Hardening:    config.HardeningStrict,
+		KMSKeyARN:    "arn:aws:kms:us-east-2:123456789012:key/abc",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	configPath := util.GetInstallConfigPath("4.12.0-x86_64")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte(`apiVersion: v1
+controlPlane:
+  name: master
+  replicas: 3
+compute:
+- name: worker
+  replicas: 3
+`), 0644)
+
+	step, err := NewStep5(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	for _, expected := range []string{"fips: true", "authentication: Required", "kmsKeyARN: arn:aws:kms:us-east-2:123456789012:key/abc"} {
+		if !util.FileContains(configPath, expected) {
+			content, _ := os.ReadFile(configPath)
+			t.Errorf("Expected %q in install-config.yaml. Content: %s", expected, string(content))
+		}
+	}
+	if details := step.Details(); len(details) != 3 || details[0].Title != "Hardening (strict)" {
+		t.Errorf("Expected the 3 settings applied by Step 5 in the details, got %+v", details)
+	}
+}
+
+func TestStep6EnablesEtcdEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Hardening:    config.HardeningBaseline,
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	step, err := NewStep6(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	manifest := filepath.Join("artifacts", "4.12.0-x86_64", "openshift", etcdEncryptionManifest)
+	if !util.FileContains(manifest, "type: aescbc") {
+		t.Error("Expected APIServer manifest enabling etcd encryption")
+	}
+	if details := step.Details(); len(details) != 1 || details[0].Line != "[Step 6] enable etcd encryption (aescbc)" {
+		t.Errorf("Expected etcd encryption in the details, got %+v", details)
+	}
+
+	// A failed step reports no setting
+	executor.SetError("artifacts/4.12.0-x86_64/bin/openshift-install create manifests --dir artifacts/4.12.0-x86_64", errors.New("failed"))
+	failed, _ := NewStep6(cfg, log, executor)
+	if err := failed.Execute(); err == nil {
+		t.Fatal("Expected create manifests to fail")
+	}
+	if details := failed.Details(); len(details) != 0 {
+		t.Errorf("Expected no details from the failed step, got %+v", details)
+	}
+}
@@ -0,0 +1,216 @@
+package steps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const iamReviewSummaryFile = "SUMMARY.md"
+
+// IAMReviewDir returns the folder holding the IAM resources to be reviewed
+func IAMReviewDir(versionArch string) string {
+	return filepath.Join("artifacts", versionArch, "iam-review")
+}
+
+// IAMReview prepares the IAM resources of Step 7 for a security review and
+// checks, before Step 7 runs, that the reviewed resources are still the ones
+// that will be created
+type IAMReview struct {
+	*BaseStep
+}
+
+func NewIAMReview(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (*IAMReview, error) {
+	base, err := newBaseStep(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	return &IAMReview{BaseStep: base}, nil
+}
+
+// Prepare collects the IAM roles, trust policies and OIDC provider that
+// Step 7 would create into the review folder and returns the review hash
+func (r *IAMReview) Prepare() (string, error) {
+	dryRunDir, err := r.ccoctlDryRun()
+	if err != nil {
+		return "", err
+	}
+	// The dry run also generates a signing key pair which must not be left around
+	defer os.RemoveAll(dryRunDir)
+
+	output, err := util.ParseDryRunOutput(dryRunDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ccoctl dry-run output: %w", err)
+	}
+
+	reviewDir := r.Dir()
+	if err := os.RemoveAll(reviewDir); err != nil {
+		return "", err
+	}
+	if err := util.EnsureDir(reviewDir); err != nil {
+		return "", err
+	}
+	for path, kind := range output.Files {
+		if kind == util.DryRunBucketFile {
+			continue
+		}
+		if err := util.CopyFile(path, filepath.Join(reviewDir, filepath.Base(path))); err != nil {
+			return "", fmt.Errorf("failed to copy %s to the review folder: %w", path, err)
+		}
+	}
+
+	hash, err := r.hash()
+	if err != nil {
+		return "", err
+	}
+
+	summary := r.summary(output, hash)
+	if err := os.WriteFile(filepath.Join(reviewDir, iamReviewSummaryFile), []byte(summary), 0644); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// Dir returns the review folder of this release
+func (r *IAMReview) Dir() string {
+	return IAMReviewDir(r.versionArch)
+}
+
+// Pending reports whether a review folder exists, i.e. Step 7 must wait for an approval
+func (r *IAMReview) Pending() bool {
+	return util.DirExistsWithFiles(r.Dir())
+}
+
+// VerifyApproval checks that neither the review folder, the credentials
+// requests nor the configuration shaping the resources changed since the
+// review hash was handed out
+func (r *IAMReview) VerifyApproval(approvedHash string) error {
+	if !r.Pending() {
+		return fmt.Errorf("no IAM review found in %s, run install --stop-for-iam-review first", r.Dir())
+	}
+	hash, err := r.hash()
+	if err != nil {
+		return err
+	}
+	if hash != strings.TrimSpace(approvedHash) {
+		return fmt.Errorf("IAM review files, credentials requests or configuration changed since the review (hash %s), prepare a new review", hash)
+	}
+	return nil
+}
+
+// hash digests the reviewed resource files together with the credentials
+// requests and the configuration they were generated from: the role names,
+// trust policies and issuer depend on the cluster name, the region and the
+// bucket, and the boundary is attached to the roles
+func (r *IAMReview) hash() (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "cluster=%s\x00region=%s\x00privateBucket=%t\x00boundary=%s\x00",
+		r.cfg.ClusterName, r.cfg.AwsRegion, r.cfg.PrivateBucket, r.cfg.PermissionsBoundaryArn)
+	for _, dir := range []string{r.Dir(), util.GetCredReqsPath(r.versionArch)} {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"))
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if filepath.Base(path) == iamReviewSummaryFile || !util.FileExists(path) {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", path, len(content))
+			h.Write(content)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *IAMReview) summary(output *util.DryRunOutput, hash string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# IAM review for cluster %s (%s)\n\n", r.cfg.ClusterName, r.cfg.AwsRegion))
+	sb.WriteString(fmt.Sprintf("Release: %s\n", r.versionArch))
+	sb.WriteString(fmt.Sprintf("Review hash: %s\n\n", hash))
+	sb.WriteString(fmt.Sprintf("Approve with: openshift-sts-installer install --approve-iam=%s\n\n", hash))
+
+	if provider := output.IdentityProvider; provider != nil {
+		sb.WriteString("## OIDC identity provider\n\n")
+		sb.WriteString(fmt.Sprintf("- URL: %s\n", provider.URL))
+		sb.WriteString(fmt.Sprintf("- Client IDs: %s\n", strings.Join(provider.ClientIDs, ", ")))
+		sb.WriteString(fmt.Sprintf("- Thumbprints: %s\n\n", strings.Join(provider.Thumbprints, ", ")))
+	}
+
+	sb.WriteString(fmt.Sprintf("## IAM roles (%d)\n", len(output.Roles)))
+	for _, role := range output.Roles {
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", role.Name))
+		if role.Description != "" {
+			sb.WriteString(role.Description + "\n\n")
+		}
+		sb.WriteString("Trust policy:\n\n```json\n")
+		sb.WriteString(prettyJSON(role.TrustPolicy))
+		sb.WriteString("\n```\n")
+		for _, policy := range role.Policies {
+			sb.WriteString(fmt.Sprintf("\nPermissions (%s):\n\n", policy.Name))
+			sb.WriteString(describePolicy(policy.Document))
+		}
+	}
+
+	return sb.String()
+}
+
+func prettyJSON(document string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(document), "", "  "); err != nil {
+		return document
+	}
+	return out.String()
+}
+
+// describePolicy renders the statements of a policy document as a list
+func describePolicy(document string) string {
+	var policy struct {
+		Statement []struct {
+			Effect   string
+			Action   interface{}
+			Resource interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return "```json\n" + prettyJSON(document) + "\n```\n"
+	}
+
+	var sb strings.Builder
+	for _, statement := range policy.Statement {
+		sb.WriteString(fmt.Sprintf("- %s %s on %s\n", statement.Effect,
+			strings.Join(stringList(statement.Action), ", "),
+			strings.Join(stringList(statement.Resource), ", ")))
+	}
+	return sb.String()
+}
+
+// stringList normalises a policy field that may be a string or a list of strings
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestIAMReviewApproval(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	credReqsDir := util.GetCredReqsPath("4.12.0-x86_64")
+	os.MkdirAll(credReqsDir, 0755)
+	credReq := filepath.Join(credReqsDir, "ingress.yaml")
+	os.WriteFile(credReq, []byte("kind: CredentialsRequest\n"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	review, err := NewIAMReview(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create IAM review: %v", err)
+	}
+	if err := review.VerifyApproval("anything"); err == nil {
+		t.Error("Expected error when no review was prepared")
+	}
+
+	hash, err := review.Prepare()
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	if !executor.WasExecutedContaining("ccoctl aws create-all --name test-cluster --region us-east-2 --credentials-requests-dir "+credReqsDir) ||
+		!executor.WasExecutedContaining("--dry-run") {
+		t.Errorf("Expected a ccoctl dry run, got %v", executor.Commands)
+	}
+	if !review.Pending() {
+		t.Error("Expected the review to be pending")
+	}
+	summary, err := os.ReadFile(filepath.Join(review.Dir(), "SUMMARY.md"))
+	if err != nil || !strings.Contains(string(summary), hash) {
+		t.Errorf("Expected the summary to contain the review hash, got %q (%v)", summary, err)
+	}
+
+	if err := review.VerifyApproval(hash); err != nil {
+		t.Errorf("Expected approval to succeed: %v", err)
+	}
+	if err := review.VerifyApproval("0000"); err == nil {
+		t.Error("Expected approval with a wrong hash to fail")
+	}
+
+	// Any change to the reviewed inputs invalidates the hash
+	for _, change := range []func(*config.Config){
+		func(c *config.Config) { c.ClusterName = "other-cluster" },
+		func(c *config.Config) { c.AwsRegion = "eu-west-1" },
+		func(c *config.Config) { c.PrivateBucket = true },
+	} {
+		changed := *cfg
+		change(&changed)
+		review, _ := NewIAMReview(&changed, log, executor)
+		if err := review.VerifyApproval(hash); err == nil {
+			t.Errorf("Expected approval to fail after a configuration change, got none for %+v", changed)
+		}
+	}
+	os.WriteFile(credReq, []byte("kind: CredentialsRequest\nspec: {}\n"), 0644)
+	if err := review.VerifyApproval(hash); err == nil {
+		t.Error("Expected approval to fail after a credentials request changed")
+	}
+}
+
+func TestIAMReviewSummary(t *testing.T) {
+	review := &IAMReview{BaseStep: &BaseStep{
+		cfg:         &config.Config{ClusterName: "test-cluster", AwsRegion: "us-east-2"},
+		versionArch: "4.12.0-x86_64",
+	}}
+	output := &util.DryRunOutput{
+		IdentityProvider: &util.DryRunIdentityProvider{URL: "https://oidc.example.com", ClientIDs: []string{"openshift"}},
+		Roles: []util.DryRunRole{{
+			Name:        "test-cluster-openshift-ingress",
+			TrustPolicy: `{"Version":"2012-10-17"}`,
+			Policies: []util.DryRunPolicy{{
+				Name:     "ingress",
+				Document: `{"Statement":[{"Effect":"Allow","Action":["route53:ListHostedZones","tag:GetResources"],"Resource":"*"}]}`,
+			}},
+		}},
+	}
+
+	summary := review.summary(output, "abc123")
+	for _, want := range []string{
+		"--approve-iam=abc123",
+		"- URL: https://oidc.example.com",
+		"### test-cluster-openshift-ingress",
+		"- Allow route53:ListHostedZones, tag:GetResources on *",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected summary to contain %q:\n%s", want, summary)
+		}
+	}
+}
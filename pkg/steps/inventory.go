@@ -0,0 +1,64 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// updateInventory applies update to the workspace inventory and saves it.
+// An inventory of another cluster or release is replaced, so that none of its
+// resources, like the infraID, end up attributed to this installation.
+func (s *BaseStep) updateInventory(update func(*util.Inventory)) error {
+	inventory, err := util.LoadInventory(util.InventoryFile)
+	if err != nil {
+		return err
+	}
+	if !inventory.Records(s.cfg.ClusterName, s.cfg.ReleaseImage) {
+		inventory = &util.Inventory{}
+	}
+	if s.cfg.ClusterName != "" {
+		inventory.ClusterName = s.cfg.ClusterName
+	}
+	if s.cfg.AwsRegion != "" {
+		inventory.Region = s.cfg.AwsRegion
+	}
+	inventory.ReleaseImage = s.cfg.ReleaseImage
+	update(inventory)
+	return inventory.Save(util.InventoryFile)
+}
+
+// recordInventory records the IAM roles, OIDC provider, S3 bucket and
+// CloudFront distribution created by ccoctl
+func (s *Step7CreateAWSResources) recordInventory(state *ccoctlState) error {
+	roleARNs, err := util.CcoctlRoleARNs(filepath.Join(s.cfg.OutputDir, "manifests"))
+	if err != nil {
+		return fmt.Errorf("failed to read role ARNs from ccoctl manifests: %w", err)
+	}
+
+	return s.updateInventory(func(inventory *util.Inventory) {
+		inventory.IAMRoles = roleARNs
+		inventory.OIDCProviderARN = state.IdentityProviderARN
+		inventory.S3Bucket = util.OIDCBucketName(s.cfg.ClusterName)
+		inventory.CloudFrontDistribution = state.CloudFrontDistributionID
+	})
+}
+
+// recordInventory records the infraID and cluster ID from metadata.json
+func (s *Step10DeployCluster) recordInventory(versionDir string) error {
+	metadata, err := util.ReadClusterMetadata(filepath.Join(versionDir, "metadata.json"))
+	if err != nil {
+		return err
+	}
+	return s.updateInventory(func(inventory *util.Inventory) {
+		inventory.InfraID = metadata.InfraID
+		inventory.ClusterID = metadata.ClusterID
+		if inventory.ClusterName == "" {
+			inventory.ClusterName = metadata.ClusterName
+		}
+		if inventory.Region == "" {
+			inventory.Region = metadata.AWS.Region
+		}
+	})
+}
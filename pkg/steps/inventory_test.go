@@ -0,0 +1,177 @@
+package steps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestInventoryRecordsCreatedResources(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+	}
+	writeCcoctlOutput(t, cfg.OutputDir)
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	step7, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step7.Execute(); err != nil {
+		t.Fatalf("Step 7 execution failed: %v", err)
+	}
+
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(versionDir, 0755)
+	os.WriteFile(filepath.Join(versionDir, "metadata.json"),
+		[]byte(`{"clusterName":"test-cluster","clusterID":"1234-abcd","infraID":"test-cluster-x7k2p","aws":{"region":"us-east-2"}}`), 0644)
+
+	step10, err := NewStep10(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step10.Execute(); err != nil {
+		t.Fatalf("Step 10 execution failed: %v", err)
+	}
+
+	inventory, err := util.LoadInventory(util.InventoryFile)
+	if err != nil {
+		t.Fatalf("Failed to load inventory: %v", err)
+	}
+	if inventory.ClusterName != "test-cluster" || inventory.Region != "us-east-2" {
+		t.Errorf("Unexpected cluster in inventory: %+v", inventory)
+	}
+	if len(inventory.IAMRoles) != 1 || inventory.IAMRoles[0] != "arn:aws:iam::123456789012:role/test-cluster-ingress" {
+		t.Errorf("Unexpected IAM roles %v", inventory.IAMRoles)
+	}
+	if inventory.OIDCProviderARN != testProviderARN || inventory.S3Bucket != "test-cluster-oidc" {
+		t.Errorf("Unexpected OIDC resources in inventory: %+v", inventory)
+	}
+	if inventory.InfraID != "test-cluster-x7k2p" || inventory.ClusterID != "1234-abcd" {
+		t.Errorf("Expected infraID and cluster ID from metadata.json, got %+v", inventory)
+	}
+}
+
+func TestParseCloudFrontDistributionID(t *testing.T) {
+	output := "2024/01/01 CloudFront distribution created with ID E2QWRUHAPOMQZL\n"
+	if id := parseCloudFrontDistributionID(output); id != "E2QWRUHAPOMQZL" {
+		t.Errorf("Expected E2QWRUHAPOMQZL, got %q", id)
+	}
+	if id := parseCloudFrontDistributionID("Identity Provider created with ARN: " + testProviderARN); id != "" {
+		t.Errorf("Expected no distribution, got %q", id)
+	}
+}
+
+func TestInventoryRecordedBeforeLaterSubStepsFail(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:           "quay.io/test:4.12.0-x86_64",
+		ClusterName:            "test-cluster",
+		AwsRegion:              "us-east-2",
+		OutputDir:              "_output",
+		PermissionsBoundaryArn: "arn:aws:iam::123456789012:policy/org-boundary",
+	}
+	executor := util.NewMockExecutor()
+	executor.SetOutput(testCreateIdentityProv, "2024/01/01 Identity Provider created with ARN: "+testProviderARN+"\n")
+	executor.SetError("aws iam put-role-permissions-boundary --role-name test-cluster-ingress --permissions-boundary arn:aws:iam::123456789012:policy/org-boundary --output json",
+		errors.New("AccessDenied"))
+	// The credentials secrets create-iam-roles writes, without any sub-step done
+	writeCcoctlOutput(t, cfg.OutputDir)
+	os.Remove(filepath.Join(cfg.OutputDir, util.CcoctlStateFile))
+
+	step7, err := NewStep7(cfg, logger.New(logger.LevelQuiet, nil), executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step7.Execute(); err == nil {
+		t.Fatal("Expected the permissions boundary to fail")
+	}
+
+	inventory, err := util.LoadInventory(util.InventoryFile)
+	if err != nil {
+		t.Fatalf("Failed to load inventory: %v", err)
+	}
+	if len(inventory.IAMRoles) != 1 || inventory.OIDCProviderARN != testProviderARN {
+		t.Errorf("Expected the roles and provider in the inventory, got %+v", inventory)
+	}
+}
+
+func TestInventoryOfAnotherClusterInWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	// Cluster A, installed and live
+	cfgA := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+	}
+	writeCcoctlOutput(t, cfgA.OutputDir)
+	step7, err := NewStep7(cfgA, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step7.Execute(); err != nil {
+		t.Fatalf("Step 7 execution failed: %v", err)
+	}
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(versionDir, 0755)
+	os.WriteFile(filepath.Join(versionDir, "metadata.json"),
+		[]byte(`{"clusterName":"test-cluster","clusterID":"1234-abcd","infraID":"test-cluster-x7k2p","aws":{"region":"us-east-2"}}`), 0644)
+	step10, err := NewStep10(cfgA, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step10.Execute(); err != nil {
+		t.Fatalf("Step 10 execution failed: %v", err)
+	}
+
+	// Cluster B of another release, stopped before Step 10, in the same workspace
+	for _, cfgB := range []*config.Config{
+		{ReleaseImage: "quay.io/test:4.13.0-x86_64", ClusterName: "test-cluster", AwsRegion: "us-east-2", OutputDir: "_output"},
+		{ReleaseImage: "quay.io/test:4.13.0-x86_64", ClusterName: "other-cluster", AwsRegion: "eu-west-1", OutputDir: "_output"},
+	} {
+		step7, err := NewStep7(cfgB, log, executor)
+		if err != nil {
+			t.Fatalf("Failed to create step: %v", err)
+		}
+		if err := step7.Execute(); err != nil {
+			t.Fatalf("Step 7 execution failed: %v", err)
+		}
+
+		inventory, err := util.LoadInventory(util.InventoryFile)
+		if err != nil {
+			t.Fatalf("Failed to load inventory: %v", err)
+		}
+		if inventory.ClusterName != cfgB.ClusterName || inventory.ReleaseImage != cfgB.ReleaseImage || inventory.Region != cfgB.AwsRegion {
+			t.Errorf("Expected the inventory of %s, got %+v", cfgB.ReleaseImage, inventory)
+		}
+		if inventory.InfraID != "" || inventory.ClusterID != "" {
+			t.Errorf("Expected no infraID or cluster ID of the previous cluster, got %+v", inventory)
+		}
+	}
+}
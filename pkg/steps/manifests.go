@@ -0,0 +1,274 @@
+package steps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// injectedManifestsFile records the manifests copied in from extraManifests
+	injectedManifestsFile = ".injected-manifests"
+	// manifestOriginalsDir keeps the unpatched copy of every patched manifest so
+	// that re-applying the patches always starts from the same content
+	manifestOriginalsDir = ".manifest-originals"
+	// manifestPatchedDir keeps the last patched copy of every patched manifest,
+	// to tell a manifest left as is by create manifests from a regenerated one
+	manifestPatchedDir = ".manifest-patched"
+)
+
+// removeInjectedManifests removes the manifests recorded by the last
+// injection, and the record. It runs before create manifests, so that no
+// manifest generated under the same name is removed.
+func removeInjectedManifests(versionDir string) error {
+	for _, previous := range InjectedManifests(versionDir) {
+		if err := os.Remove(filepath.Join(versionDir, previous)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s, injected by an earlier run: %w", previous, err)
+		}
+	}
+	if err := os.Remove(filepath.Join(versionDir, injectedManifestsFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// injectExtraManifests copies the extraManifests files, and the manifests found
+// in extraManifests directories, into openshift/. It runs after create
+// manifests and refuses manifests named like one generated by openshift-install
+// or ccoctl. It returns the injected manifests relative to the version
+// directory.
+func injectExtraManifests(versionDir string, sources []string) ([]string, error) {
+	openshiftDir := filepath.Join(versionDir, "openshift")
+	if err := util.EnsureDir(openshiftDir); err != nil {
+		return nil, err
+	}
+	generated, err := generatedManifestNames(versionDir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]string{}
+	var injected []string
+	for _, source := range sources {
+		files, err := manifestFiles(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := filepath.Base(file)
+			if other, ok := seen[name]; ok {
+				return nil, fmt.Errorf("extra manifests %s and %s have the same file name", other, file)
+			}
+			seen[name] = file
+			if by, ok := generated[name]; ok {
+				return nil, fmt.Errorf("extra manifest %s has the name of a manifest generated by %s, rename it", file, by)
+			}
+
+			if err := util.CopyFile(file, filepath.Join(openshiftDir, name)); err != nil {
+				return nil, fmt.Errorf("failed to copy %s: %w", file, err)
+			}
+			injected = append(injected, filepath.Join("openshift", name))
+		}
+	}
+
+	record := strings.Join(injected, "\n")
+	if err := os.WriteFile(filepath.Join(versionDir, injectedManifestsFile), []byte(record), 0644); err != nil {
+		return nil, err
+	}
+
+	return injected, nil
+}
+
+// InjectedManifests returns the manifests recorded by the last injection,
+// relative to the version directory
+func InjectedManifests(versionDir string) []string {
+	content, err := os.ReadFile(filepath.Join(versionDir, injectedManifestsFile))
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	return strings.Split(string(content), "\n")
+}
+
+// generatedManifestNames returns the file names of the manifests generated
+// by openshift-install, and of those ccoctl generates for the credentials
+// requests, mapped to the tool generating them
+func generatedManifestNames(versionDir string) (map[string]string, error) {
+	names := map[string]string{}
+	for _, dir := range []string{"manifests", "openshift"} {
+		entries, err := os.ReadDir(filepath.Join(versionDir, dir))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			names[entry.Name()] = "openshift-install"
+		}
+	}
+
+	requests, err := util.ReadCredentialsRequests(filepath.Join(versionDir, "credreqs"))
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		ref := request.Spec.SecretRef
+		names[fmt.Sprintf("%s-%s-credentials.yaml", ref.Namespace, ref.Name)] = "ccoctl"
+	}
+	names[authenticationConfigManifest] = "ccoctl"
+	return names, nil
+}
+
+func manifestFiles(source string) ([]string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("extra manifest %s: %w", source, err)
+	}
+	if !info.IsDir() {
+		return []string{source}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(source, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// refreshManifestOriginals runs right after create manifests. A patch target
+// still holding the last patched content was left as is, and is restored from
+// its unpatched copy so the patches are not applied twice. Any other target
+// was regenerated and becomes the new unpatched copy.
+func refreshManifestOriginals(versionDir string, patches []config.ManifestPatch) error {
+	refreshed := map[string]bool{}
+	for _, patch := range patches {
+		if refreshed[patch.Target] {
+			continue
+		}
+		refreshed[patch.Target] = true
+
+		target := filepath.Join(versionDir, patch.Target)
+		original := filepath.Join(versionDir, manifestOriginalsDir, patch.Target)
+		patched := filepath.Join(versionDir, manifestPatchedDir, patch.Target)
+
+		if util.FileExists(original) && sameContent(target, patched) {
+			if err := util.CopyFile(original, target); err != nil {
+				return fmt.Errorf("failed to restore original of %s: %w", patch.Target, err)
+			}
+			continue
+		}
+		if err := util.EnsureDir(filepath.Dir(original)); err != nil {
+			return err
+		}
+		if err := util.CopyFile(target, original); err != nil {
+			return fmt.Errorf("failed to save original of %s: %w", patch.Target, err)
+		}
+	}
+	return nil
+}
+
+// applyManifestPatches applies the configured patches to the manifests in the
+// version directory and keeps a copy of each patched target
+func applyManifestPatches(versionDir string, patches []config.ManifestPatch) error {
+	var targets []string
+	for _, patch := range patches {
+		target := filepath.Join(versionDir, patch.Target)
+		if err := applyManifestPatch(target, patch); err != nil {
+			return fmt.Errorf("failed to patch %s with %s: %w", patch.Target, patch.Patch, err)
+		}
+		if !slices.Contains(targets, patch.Target) {
+			targets = append(targets, patch.Target)
+		}
+	}
+
+	for _, target := range targets {
+		patched := filepath.Join(versionDir, manifestPatchedDir, target)
+		if err := util.EnsureDir(filepath.Dir(patched)); err != nil {
+			return err
+		}
+		if err := util.CopyFile(filepath.Join(versionDir, target), patched); err != nil {
+			return fmt.Errorf("failed to save patched copy of %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+func sameContent(a, b string) bool {
+	contentA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	contentB, err := os.ReadFile(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(contentA, contentB)
+}
+
+func applyManifestPatch(target string, patch config.ManifestPatch) error {
+	doc, err := readSingleDocument(target)
+	if err != nil {
+		return err
+	}
+	patchContent, err := os.ReadFile(patch.Patch)
+	if err != nil {
+		return err
+	}
+
+	switch patch.Type {
+	case config.PatchTypeJSON6902:
+		var ops []util.PatchOperation
+		if err := yaml.Unmarshal(patchContent, &ops); err != nil {
+			return fmt.Errorf("failed to parse patch: %w", err)
+		}
+		if doc, err = util.ApplyJSONPatch(doc, ops); err != nil {
+			return err
+		}
+	case config.PatchTypeStrategicMerge:
+		var merge interface{}
+		if err := yaml.Unmarshal(patchContent, &merge); err != nil {
+			return fmt.Errorf("failed to parse patch: %w", err)
+		}
+		doc = util.StrategicMerge(doc, merge)
+	default:
+		return fmt.Errorf("unknown patch type %q", patch.Type)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, out, 0644)
+}
+
+func readSingleDocument(path string) (interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var extra interface{}
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return nil, fmt.Errorf("%s contains more than one document, which is not supported", path)
+	}
+	return doc, nil
+}
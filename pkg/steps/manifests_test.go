@@ -0,0 +1,175 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestStep6InjectsAndPatchesManifests(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(versionDir, "manifests"), 0755)
+	generated := filepath.Join(versionDir, "manifests", "cluster-network-02-config.yml")
+	os.WriteFile(generated, []byte("kind: Network\nspec:\n  networkType: OVNKubernetes\n"), 0644)
+
+	os.MkdirAll("extra/nested", 0755)
+	os.WriteFile("extra/99-machineconfig.yaml", []byte("kind: MachineConfig\n"), 0644)
+	os.WriteFile("extra/nested/99-ca.yaml", []byte("kind: ConfigMap\n"), 0644)
+	os.WriteFile("extra/README.md", []byte("not a manifest"), 0644)
+	os.WriteFile("network-patch.yaml", []byte("- op: add\n  path: /spec/mtu\n  value: 8901\n"), 0644)
+	os.WriteFile("network-merge.yaml", []byte("metadata:\n  name: cluster\n"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ExtraManifests: []string{"extra"},
+		ManifestPatches: []config.ManifestPatch{
+			{Target: "manifests/cluster-network-02-config.yml", Type: config.PatchTypeJSON6902, Patch: "network-patch.yaml"},
+			{Target: "manifests/cluster-network-02-config.yml", Type: config.PatchTypeStrategicMerge, Patch: "network-merge.yaml"},
+		},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	step, err := NewStep6(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	first, _ := os.ReadFile(generated)
+
+	// Re-running the step must not apply the patches twice
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Second step execution failed: %v", err)
+	}
+	second, _ := os.ReadFile(generated)
+
+	if string(first) != string(second) {
+		t.Errorf("Patches are not idempotent:\n%s\nvs\n%s", first, second)
+	}
+	if !util.FileContains(generated, "mtu: 8901") || !util.FileContains(generated, "name: cluster") {
+		t.Errorf("Patches were not applied: %s", second)
+	}
+
+	for _, name := range []string{"99-machineconfig.yaml", "99-ca.yaml"} {
+		if !util.FileExists(filepath.Join(versionDir, "openshift", name)) {
+			t.Errorf("Expected %s to be injected", name)
+		}
+	}
+	if util.FileExists(filepath.Join(versionDir, "openshift", "README.md")) {
+		t.Error("Non-manifest files should not be injected")
+	}
+	if injected := InjectedManifests(versionDir); len(injected) != 2 {
+		t.Errorf("Expected 2 recorded injected manifests, got %v", injected)
+	}
+}
+
+func TestStep6RegeneratedManifests(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(versionDir, "manifests"), 0755)
+	generated := filepath.Join(versionDir, "manifests", "cluster-network-02-config.yml")
+	os.WriteFile(generated, []byte("kind: Network\nspec:\n  networkType: OVNKubernetes\n"), 0644)
+	os.WriteFile("99-machineconfig.yaml", []byte("kind: MachineConfig\n"), 0644)
+	os.WriteFile("network-patch.yaml", []byte("- op: add\n  path: /spec/mtu\n  value: 8901\n"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ExtraManifests: []string{"99-machineconfig.yaml"},
+		ManifestPatches: []config.ManifestPatch{
+			{Target: "manifests/cluster-network-02-config.yml", Type: config.PatchTypeJSON6902, Patch: "network-patch.yaml"},
+		},
+	}
+	step, err := NewStep6(cfg, logger.New(logger.LevelQuiet, nil), util.NewMockExecutor())
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	// create manifests regenerates the target, the stale original must not come back
+	os.WriteFile(generated, []byte("kind: Network\nspec:\n  networkType: OpenShiftSDN\n"), 0644)
+	cfg.ExtraManifests = nil
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Second step execution failed: %v", err)
+	}
+	if !util.FileContains(generated, "OpenShiftSDN") || !util.FileContains(generated, "mtu: 8901") {
+		content, _ := os.ReadFile(generated)
+		t.Errorf("Expected the regenerated manifest to be patched, got:\n%s", content)
+	}
+
+	// Manifests no longer in extraManifests are removed with their record
+	if util.FileExists(filepath.Join(versionDir, "openshift", "99-machineconfig.yaml")) {
+		t.Error("Expected the manifest no longer configured to be removed")
+	}
+	if injected := InjectedManifests(versionDir); len(injected) != 0 {
+		t.Errorf("Expected no recorded injected manifests, got %v", injected)
+	}
+}
+
+func TestStep6RefusesGeneratedManifestNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(versionDir, "openshift"), 0755)
+	os.MkdirAll(filepath.Join(versionDir, "credreqs"), 0755)
+	os.WriteFile(filepath.Join(versionDir, "openshift", "99_openshift-cluster-api_worker-user-data-secret.yaml"), []byte("kind: Secret\n"), 0644)
+	os.WriteFile(filepath.Join(versionDir, "credreqs", "0000_50_cluster-ingress-operator_00-ingress-credentials-request.yaml"), []byte(`apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-ingress
+spec:
+  secretRef:
+    name: cloud-credentials
+    namespace: openshift-ingress-operator
+`), 0644)
+	os.MkdirAll("extra", 0755)
+
+	cfg := &config.Config{ReleaseImage: "quay.io/test:4.12.0-x86_64"}
+	step, err := NewStep6(cfg, logger.New(logger.LevelQuiet, nil), util.NewMockExecutor())
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+
+	// Nothing configured or recorded, nothing injected
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if util.FileExists(filepath.Join(versionDir, injectedManifestsFile)) {
+		t.Error("Expected no injection record without extraManifests")
+	}
+
+	for _, name := range []string{
+		"99_openshift-cluster-api_worker-user-data-secret.yaml",
+		"openshift-ingress-operator-cloud-credentials-credentials.yaml",
+		"cluster-authentication-02-config.yaml",
+	} {
+		extra := filepath.Join("extra", name)
+		os.WriteFile(extra, []byte("kind: ConfigMap\n"), 0644)
+		cfg.ExtraManifests = []string{extra}
+		if err := step.Execute(); err == nil {
+			t.Errorf("Expected %s to be refused", name)
+		}
+	}
+	if !util.FileContains(filepath.Join(versionDir, "openshift", "99_openshift-cluster-api_worker-user-data-secret.yaml"), "kind: Secret") {
+		t.Error("Expected the generated manifest to be kept")
+	}
+}
@@ -0,0 +1,201 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// PermissionSet is a group of IAM actions a step needs from the installing
+// identity
+type PermissionSet struct {
+	Step        int
+	Description string
+	Actions     []string
+}
+
+// Permissions of openshift-install, from the documented requirements of an
+// installer-provisioned AWS cluster, and of ccoctl aws create-all
+var (
+	installConfigPermissions = PermissionSet{4, "list the base domains for install-config.yaml", []string{
+		"route53:ListHostedZones",
+	}}
+
+	preflightPermissions = PermissionSet{7, "preflight checks", []string{
+		"ec2:DescribeAddresses", "ec2:DescribeAvailabilityZones", "ec2:DescribeInstanceTypeOfferings",
+		"ec2:DescribeInstanceTypes", "ec2:DescribeInstances", "ec2:DescribeVpcs",
+		"elasticloadbalancing:DescribeLoadBalancers",
+		"iam:ListOpenIDConnectProviders",
+		"route53:ListHostedZonesByName",
+		"s3:ListBucket",
+		"servicequotas:GetAWSDefaultServiceQuota", "servicequotas:GetServiceQuota",
+	}}
+
+	oidcPermissions = PermissionSet{7, "OIDC bucket and identity provider (ccoctl)", []string{
+		"iam:CreateOpenIDConnectProvider", "iam:GetOpenIDConnectProvider", "iam:ListOpenIDConnectProviders",
+		"iam:TagOpenIDConnectProvider",
+		"s3:CreateBucket", "s3:GetBucketAcl", "s3:GetBucketTagging", "s3:GetObject", "s3:GetObjectAcl",
+		"s3:GetObjectTagging", "s3:ListBucket", "s3:PutBucketAcl", "s3:PutBucketPolicy",
+		"s3:PutBucketPublicAccessBlock", "s3:PutBucketTagging", "s3:PutObject", "s3:PutObjectAcl",
+		"s3:PutObjectTagging",
+	}}
+
+	cloudFrontPermissions = PermissionSet{7, "CloudFront distribution of the private OIDC bucket (ccoctl)", []string{
+		"cloudfront:CreateCloudFrontOriginAccessIdentity", "cloudfront:CreateDistribution",
+		"cloudfront:ListCloudFrontOriginAccessIdentities", "cloudfront:ListDistributions",
+		"cloudfront:ListTagsForResource", "cloudfront:TagResource",
+	}}
+
+	iamRolePermissions = PermissionSet{7, "IAM roles of the credentials requests (ccoctl)", []string{
+		"iam:CreateRole", "iam:GetRole", "iam:GetUser", "iam:ListRolePolicies", "iam:ListRoles",
+		"iam:PutRolePolicy", "iam:TagRole",
+	}}
+
+	boundaryPermissions = PermissionSet{7, "permissions boundary of the IAM roles", []string{
+		"iam:GetRole", "iam:PutRolePermissionsBoundary",
+	}}
+
+	ec2Permissions = PermissionSet{10, "EC2 instances and security groups", []string{
+		"ec2:AttachNetworkInterface", "ec2:AuthorizeSecurityGroupEgress", "ec2:AuthorizeSecurityGroupIngress",
+		"ec2:CopyImage", "ec2:CreateNetworkInterface", "ec2:CreateSecurityGroup", "ec2:CreateTags",
+		"ec2:CreateVolume", "ec2:DeleteSecurityGroup", "ec2:DeleteSnapshot", "ec2:DeleteTags",
+		"ec2:DeregisterImage", "ec2:DescribeAccountAttributes", "ec2:DescribeAddresses",
+		"ec2:DescribeAvailabilityZones", "ec2:DescribeDhcpOptions", "ec2:DescribeImages",
+		"ec2:DescribeInstanceAttribute", "ec2:DescribeInstanceCreditSpecifications", "ec2:DescribeInstances",
+		"ec2:DescribeInstanceTypeOfferings", "ec2:DescribeInstanceTypes", "ec2:DescribeInternetGateways",
+		"ec2:DescribeKeyPairs", "ec2:DescribeNatGateways", "ec2:DescribeNetworkAcls",
+		"ec2:DescribeNetworkInterfaces", "ec2:DescribePrefixLists", "ec2:DescribeRegions",
+		"ec2:DescribeRouteTables", "ec2:DescribeSecurityGroupRules", "ec2:DescribeSecurityGroups",
+		"ec2:DescribeSubnets", "ec2:DescribeTags", "ec2:DescribeVolumes", "ec2:DescribeVpcAttribute",
+		"ec2:DescribeVpcEndpoints", "ec2:DescribeVpcs", "ec2:GetEbsDefaultKmsKeyId",
+		"ec2:ModifyInstanceAttribute", "ec2:ModifyNetworkInterfaceAttribute", "ec2:RevokeSecurityGroupEgress",
+		"ec2:RevokeSecurityGroupIngress", "ec2:RunInstances", "ec2:TerminateInstances",
+	}}
+
+	vpcPermissions = PermissionSet{10, "VPC, subnets and NAT gateways", []string{
+		"ec2:AllocateAddress", "ec2:AssociateAddress", "ec2:AssociateDhcpOptions", "ec2:AssociateRouteTable",
+		"ec2:AttachInternetGateway", "ec2:CreateDhcpOptions", "ec2:CreateInternetGateway",
+		"ec2:CreateNatGateway", "ec2:CreateRoute", "ec2:CreateRouteTable", "ec2:CreateSubnet",
+		"ec2:CreateVpc", "ec2:CreateVpcEndpoint", "ec2:ModifySubnetAttribute", "ec2:ModifyVpcAttribute",
+	}}
+
+	loadBalancerPermissions = PermissionSet{10, "API and ingress load balancers", []string{
+		"elasticloadbalancing:AddTags", "elasticloadbalancing:ApplySecurityGroupsToLoadBalancer",
+		"elasticloadbalancing:AttachLoadBalancerToSubnets", "elasticloadbalancing:ConfigureHealthCheck",
+		"elasticloadbalancing:CreateListener", "elasticloadbalancing:CreateLoadBalancer",
+		"elasticloadbalancing:CreateLoadBalancerListeners", "elasticloadbalancing:CreateTargetGroup",
+		"elasticloadbalancing:DeleteLoadBalancer", "elasticloadbalancing:DeregisterInstancesFromLoadBalancer",
+		"elasticloadbalancing:DeregisterTargets", "elasticloadbalancing:DescribeInstanceHealth",
+		"elasticloadbalancing:DescribeListeners", "elasticloadbalancing:DescribeLoadBalancerAttributes",
+		"elasticloadbalancing:DescribeLoadBalancers", "elasticloadbalancing:DescribeTags",
+		"elasticloadbalancing:DescribeTargetGroupAttributes", "elasticloadbalancing:DescribeTargetHealth",
+		"elasticloadbalancing:ModifyLoadBalancerAttributes", "elasticloadbalancing:ModifyTargetGroup",
+		"elasticloadbalancing:ModifyTargetGroupAttributes", "elasticloadbalancing:RegisterInstancesWithLoadBalancer",
+		"elasticloadbalancing:RegisterTargets", "elasticloadbalancing:SetLoadBalancerPoliciesOfListener",
+		"elasticloadbalancing:SetSecurityGroups",
+	}}
+
+	instanceProfilePermissions = PermissionSet{10, "instance profiles of the control plane and workers", []string{
+		"iam:AddRoleToInstanceProfile", "iam:CreateInstanceProfile", "iam:CreateRole",
+		"iam:DeleteInstanceProfile", "iam:DeleteRole", "iam:DeleteRolePolicy", "iam:GetInstanceProfile",
+		"iam:GetRole", "iam:GetRolePolicy", "iam:GetUser", "iam:ListInstanceProfilesForRole", "iam:ListRoles",
+		"iam:ListUsers", "iam:PassRole", "iam:PutRolePolicy", "iam:RemoveRoleFromInstanceProfile",
+		"iam:SimulatePrincipalPolicy", "iam:TagInstanceProfile", "iam:TagRole",
+	}}
+
+	route53Permissions = PermissionSet{10, "cluster DNS records and private zone", []string{
+		"route53:ChangeResourceRecordSets", "route53:ChangeTagsForResource", "route53:CreateHostedZone",
+		"route53:DeleteHostedZone", "route53:GetChange", "route53:GetHostedZone", "route53:ListHostedZones",
+		"route53:ListHostedZonesByName", "route53:ListResourceRecordSets", "route53:ListTagsForResource",
+		"route53:UpdateHostedZoneComment",
+	}}
+
+	bootstrapPermissions = PermissionSet{10, "bootstrap ignition bucket", []string{
+		"s3:CreateBucket", "s3:DeleteBucket", "s3:DeleteObject", "s3:GetAccelerateConfiguration",
+		"s3:GetBucketAcl", "s3:GetBucketCors", "s3:GetBucketLocation", "s3:GetBucketLogging",
+		"s3:GetBucketObjectLockConfiguration", "s3:GetBucketPolicy", "s3:GetBucketRequestPayment",
+		"s3:GetBucketTagging", "s3:GetBucketVersioning", "s3:GetBucketWebsite",
+		"s3:GetEncryptionConfiguration", "s3:GetLifecycleConfiguration", "s3:GetObject", "s3:GetObjectAcl",
+		"s3:GetObjectTagging", "s3:GetObjectVersion", "s3:GetReplicationConfiguration", "s3:ListBucket",
+		"s3:PutBucketAcl", "s3:PutBucketPolicy", "s3:PutBucketTagging", "s3:PutEncryptionConfiguration",
+		"s3:PutObject", "s3:PutObjectAcl", "s3:PutObjectTagging",
+		"servicequotas:ListAWSDefaultServiceQuotas", "tag:GetResources",
+	}}
+
+	kmsPermissions = PermissionSet{10, "customer-managed KMS key of the root volumes", []string{
+		"kms:CreateGrant", "kms:Decrypt", "kms:DescribeKey", "kms:Encrypt", "kms:GenerateDataKey",
+		"kms:GenerateDataKeyWithoutPlaintext", "kms:ListGrants", "kms:ReEncryptFrom", "kms:ReEncryptTo",
+		"kms:RevokeGrant",
+	}}
+)
+
+// RequiredPermissions returns the permission sets the configured options
+// need, in step order. installConfig may be nil before Step 4; with existing
+// subnets in it no VPC is created.
+func RequiredPermissions(cfg *config.Config, installConfig *util.InstallConfig) []PermissionSet {
+	sets := []PermissionSet{installConfigPermissions}
+	if !cfg.SkipPreflight {
+		sets = append(sets, preflightPermissions)
+	}
+	if cfg.BYOIAM == nil {
+		sets = append(sets, oidcPermissions)
+		if cfg.PrivateBucket || hardeningEnabled(cfg, HardeningPrivateOIDCBucket) {
+			sets = append(sets, cloudFrontPermissions)
+		}
+		sets = append(sets, iamRolePermissions)
+		if cfg.PermissionsBoundaryArn != "" {
+			sets = append(sets, boundaryPermissions)
+		}
+	}
+	sets = append(sets, ec2Permissions)
+	if installConfig == nil || len(installConfig.Platform.AWS.Subnets) == 0 {
+		sets = append(sets, vpcPermissions)
+	}
+	sets = append(sets, loadBalancerPermissions, instanceProfilePermissions, route53Permissions, bootstrapPermissions)
+	if cfg.KMSKeyARN != "" {
+		sets = append(sets, kmsPermissions)
+	}
+	return sets
+}
+
+// policyStatement is a statement of an IAM policy document
+type policyStatement struct {
+	Sid      string   `json:"Sid"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource string   `json:"Resource"`
+}
+
+// RequiredPolicy returns an IAM policy document allowing the actions of the
+// permission sets, with one statement per step
+func RequiredPolicy(sets []PermissionSet) ([]byte, error) {
+	actions := map[int]map[string]bool{}
+	var steps []int
+	for _, set := range sets {
+		if actions[set.Step] == nil {
+			actions[set.Step] = map[string]bool{}
+			steps = append(steps, set.Step)
+		}
+		for _, action := range set.Actions {
+			actions[set.Step][action] = true
+		}
+	}
+	sort.Ints(steps)
+
+	policy := struct {
+		Version   string            `json:"Version"`
+		Statement []policyStatement `json:"Statement"`
+	}{Version: "2012-10-17"}
+	for _, step := range steps {
+		statement := policyStatement{Sid: fmt.Sprintf("Step%d", step), Effect: "Allow", Resource: "*"}
+		for action := range actions[step] {
+			statement.Action = append(statement.Action, action)
+		}
+		sort.Strings(statement.Action)
+		policy.Statement = append(policy.Statement, statement)
+	}
+	return json.MarshalIndent(policy, "", "  ")
+}
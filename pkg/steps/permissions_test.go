@@ -0,0 +1,91 @@
+package steps
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	descriptions := func(sets []PermissionSet) []string {
+		var names []string
+		for _, set := range sets {
+			names = append(names, set.Description)
+		}
+		return names
+	}
+
+	sets := RequiredPermissions(&config.Config{}, nil)
+	for _, want := range []PermissionSet{installConfigPermissions, preflightPermissions, oidcPermissions, iamRolePermissions, vpcPermissions} {
+		if !slices.Contains(descriptions(sets), want.Description) {
+			t.Errorf("Expected %q by default", want.Description)
+		}
+	}
+	for _, unwanted := range []PermissionSet{cloudFrontPermissions, boundaryPermissions, kmsPermissions} {
+		if slices.Contains(descriptions(sets), unwanted.Description) {
+			t.Errorf("Expected no %q by default", unwanted.Description)
+		}
+	}
+	for i := 1; i < len(sets); i++ {
+		if sets[i].Step < sets[i-1].Step {
+			t.Errorf("Expected the permission sets in step order, got %v", descriptions(sets))
+		}
+	}
+
+	cfg := &config.Config{
+		Hardening:              config.HardeningStrict,
+		KMSKeyARN:              "arn:aws:kms:us-east-2:123456789012:key/abc",
+		PermissionsBoundaryArn: "arn:aws:iam::123456789012:policy/boundary",
+		SkipPreflight:          true,
+	}
+	installConfig := &util.InstallConfig{}
+	installConfig.Platform.AWS.Subnets = []string{"subnet-0abc"}
+	got := descriptions(RequiredPermissions(cfg, installConfig))
+	for _, want := range []PermissionSet{cloudFrontPermissions, boundaryPermissions, kmsPermissions} {
+		if !slices.Contains(got, want.Description) {
+			t.Errorf("Expected %q, got %v", want.Description, got)
+		}
+	}
+	for _, unwanted := range []PermissionSet{preflightPermissions, vpcPermissions} {
+		if slices.Contains(got, unwanted.Description) {
+			t.Errorf("Expected no %q, got %v", unwanted.Description, got)
+		}
+	}
+
+	// byoIAM roles and issuer are not created by Step 7
+	got = descriptions(RequiredPermissions(&config.Config{BYOIAM: &config.BYOIAMConfig{}, PrivateBucket: true}, nil))
+	for _, unwanted := range []PermissionSet{oidcPermissions, cloudFrontPermissions, iamRolePermissions} {
+		if slices.Contains(got, unwanted.Description) {
+			t.Errorf("Expected no %q with byoIAM, got %v", unwanted.Description, got)
+		}
+	}
+}
+
+func TestRequiredPolicy(t *testing.T) {
+	content, err := RequiredPolicy([]PermissionSet{
+		{7, "roles", []string{"iam:TagRole", "iam:CreateRole"}},
+		{4, "zones", []string{"route53:ListHostedZones"}},
+		{7, "boundary", []string{"iam:GetRole", "iam:CreateRole"}},
+	})
+	if err != nil {
+		t.Fatalf("RequiredPolicy failed: %v", err)
+	}
+	var policy struct {
+		Version   string
+		Statement []policyStatement
+	}
+	if err := json.Unmarshal(content, &policy); err != nil {
+		t.Fatalf("Invalid policy document: %v", err)
+	}
+	if policy.Version != "2012-10-17" || len(policy.Statement) != 2 {
+		t.Fatalf("Unexpected policy %s", content)
+	}
+	step7 := policy.Statement[1]
+	if step7.Sid != "Step7" || step7.Effect != "Allow" || step7.Resource != "*" ||
+		!slices.Equal(step7.Action, []string{"iam:CreateRole", "iam:GetRole", "iam:TagRole"}) {
+		t.Errorf("Expected one sorted statement per step, got %+v", step7)
+	}
+}
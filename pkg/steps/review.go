@@ -0,0 +1,289 @@
+package steps
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// Manifest origins shown by the review gate
+const (
+	OriginGenerated = "generated"
+	OriginCcoctl    = "ccoctl"
+	OriginInjected  = "user-injected"
+)
+
+const (
+	// reviewedSetFile holds the manifest set approved by the last review
+	reviewedSetFile = ".manifest-review.json"
+	// ReviewLogFile is the audit log of review decisions
+	ReviewLogFile = "manifest-review.log"
+)
+
+// ReviewManifest is a manifest that will be installed into the cluster
+type ReviewManifest struct {
+	Path   string // as shown to the reviewer
+	File   string // location on disk
+	Origin string
+	Hash   string
+}
+
+// ReviewDecision is an entry of the review audit log
+type ReviewDecision struct {
+	Time      time.Time `json:"time"`
+	Reviewer  string    `json:"reviewer"`
+	Decision  string    `json:"decision"`
+	Manifests int       `json:"manifests"`
+	Added     []string  `json:"added,omitempty"`
+	Removed   []string  `json:"removed,omitempty"`
+	Changed   []string  `json:"changed,omitempty"`
+}
+
+// CollectManifests lists the manifests in the version directory and the ccoctl
+// output directory, grouped by where they come from
+func CollectManifests(versionDir, outputDir string) ([]ReviewManifest, error) {
+	ccoctlNames := map[string]bool{}
+	ccoctlDir := filepath.Join(outputDir, "manifests")
+	ccoctlFiles, _ := filepath.Glob(filepath.Join(ccoctlDir, "*"))
+	for _, file := range ccoctlFiles {
+		ccoctlNames[filepath.Base(file)] = true
+	}
+
+	injected := map[string]bool{}
+	for _, path := range InjectedManifests(versionDir) {
+		injected[path] = true
+	}
+
+	var manifests []ReviewManifest
+	for _, dir := range []string{"manifests", "openshift"} {
+		files, _ := filepath.Glob(filepath.Join(versionDir, dir, "*"))
+		for _, file := range files {
+			if !util.FileExists(file) {
+				continue
+			}
+			rel := filepath.Join(dir, filepath.Base(file))
+			origin := OriginGenerated
+			if injected[rel] {
+				origin = OriginInjected
+			} else if ccoctlNames[filepath.Base(file)] {
+				// Already copied from the ccoctl output, listed below
+				continue
+			}
+			manifests = append(manifests, ReviewManifest{Path: rel, File: file, Origin: origin})
+		}
+	}
+	for _, file := range ccoctlFiles {
+		if util.FileExists(file) {
+			manifests = append(manifests, ReviewManifest{Path: filepath.Join("_output", "manifests", filepath.Base(file)), File: file, Origin: OriginCcoctl})
+		}
+	}
+
+	for i := range manifests {
+		hash, err := fileSHA256(manifests[i].File)
+		if err != nil {
+			return nil, err
+		}
+		manifests[i].Hash = hash
+	}
+
+	return manifests, nil
+}
+
+// DiffManifests compares the manifests with the set approved by the last review
+func DiffManifests(previous map[string]string, manifests []ReviewManifest) (added, removed, changed []string) {
+	current := map[string]bool{}
+	for _, m := range manifests {
+		current[m.Path] = true
+		hash, ok := previous[m.Path]
+		if !ok {
+			added = append(added, m.Path)
+		} else if hash != m.Hash {
+			changed = append(changed, m.Path)
+		}
+	}
+	for path := range previous {
+		if !current[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	return added, removed, changed
+}
+
+// ReviewGate asks a reviewer to approve the manifests before the cluster is deployed
+type ReviewGate struct {
+	VersionDir string
+	OutputDir  string
+	In         io.Reader
+	Out        io.Writer
+}
+
+// Run shows the manifests and records the reviewer's decision. It returns
+// true when the manifests were approved.
+func (g *ReviewGate) Run() (bool, error) {
+	manifests, err := CollectManifests(g.VersionDir, g.OutputDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to collect manifests: %w", err)
+	}
+
+	previous, hasPrevious := g.previousSet()
+	added, removed, changed := DiffManifests(previous, manifests)
+
+	reader := bufio.NewReader(g.In)
+	g.printManifests(manifests)
+	if hasPrevious {
+		g.printDiff(added, removed, changed)
+	} else {
+		fmt.Fprintln(g.Out, "No previous review found, all manifests are new.")
+	}
+
+	decision := ""
+	for decision == "" {
+		fmt.Fprint(g.Out, "Enter a number to view a manifest, 'l' to list again, 'a' to approve, 'r' to reject: ")
+		answer, err := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if err != nil && answer == "" {
+			answer = "r"
+		}
+
+		switch answer {
+		case "a", "approve":
+			decision = "approved"
+		case "r", "reject":
+			decision = "rejected"
+		case "l", "list":
+			g.printManifests(manifests)
+		default:
+			index, convErr := strconv.Atoi(answer)
+			if convErr != nil || index < 1 || index > len(manifests) {
+				fmt.Fprintln(g.Out, "Invalid choice.")
+				continue
+			}
+			content, readErr := os.ReadFile(manifests[index-1].File)
+			if readErr != nil {
+				fmt.Fprintf(g.Out, "Cannot read %s: %v\n", manifests[index-1].File, readErr)
+				continue
+			}
+			fmt.Fprintf(g.Out, "--- %s (%s) ---\n%s\n", manifests[index-1].Path, manifests[index-1].Origin, content)
+		}
+	}
+
+	defaultReviewer := os.Getenv("USER")
+	fmt.Fprintf(g.Out, "Reviewer name [%s]: ", defaultReviewer)
+	reviewer, _ := reader.ReadString('\n')
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		reviewer = defaultReviewer
+	}
+
+	entry := ReviewDecision{
+		Time:      time.Now().UTC(),
+		Reviewer:  reviewer,
+		Decision:  decision,
+		Manifests: len(manifests),
+		Added:     added,
+		Removed:   removed,
+		Changed:   changed,
+	}
+	if err := g.record(entry, manifests); err != nil {
+		return false, err
+	}
+
+	return decision == "approved", nil
+}
+
+func (g *ReviewGate) printManifests(manifests []ReviewManifest) {
+	fmt.Fprintf(g.Out, "\nManifests to be installed (%d):\n", len(manifests))
+	for _, origin := range []string{OriginGenerated, OriginCcoctl, OriginInjected} {
+		var lines []string
+		for i, m := range manifests {
+			if m.Origin == origin {
+				lines = append(lines, fmt.Sprintf("  [%d] %s", i+1, m.Path))
+			}
+		}
+		fmt.Fprintf(g.Out, "\n%s (%d):\n", origin, len(lines))
+		for _, line := range lines {
+			fmt.Fprintln(g.Out, line)
+		}
+	}
+	fmt.Fprintln(g.Out)
+}
+
+func (g *ReviewGate) printDiff(added, removed, changed []string) {
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Fprintln(g.Out, "No changes since the previous review.")
+		return
+	}
+	fmt.Fprintln(g.Out, "Changes since the previous review:")
+	for _, path := range added {
+		fmt.Fprintf(g.Out, "  + %s\n", path)
+	}
+	for _, path := range removed {
+		fmt.Fprintf(g.Out, "  - %s\n", path)
+	}
+	for _, path := range changed {
+		fmt.Fprintf(g.Out, "  ~ %s\n", path)
+	}
+}
+
+func (g *ReviewGate) previousSet() (map[string]string, bool) {
+	content, err := os.ReadFile(filepath.Join(g.VersionDir, reviewedSetFile))
+	if err != nil {
+		return map[string]string{}, false
+	}
+	set := map[string]string{}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return map[string]string{}, false
+	}
+	return set, true
+}
+
+// record appends the decision to the audit log and, on approval, remembers the
+// manifest set for the next review
+func (g *ReviewGate) record(entry ReviewDecision, manifests []ReviewManifest) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(g.VersionDir, ReviewLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open review log: %w", err)
+	}
+	defer logFile.Close()
+	if _, err := logFile.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write review log: %w", err)
+	}
+
+	if entry.Decision != "approved" {
+		return nil
+	}
+	set := map[string]string{}
+	for _, m := range manifests {
+		set[m.Path] = m.Hash
+	}
+	content, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.VersionDir, reviewedSetFile), content, 0644)
+}
+
+func fileSHA256(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
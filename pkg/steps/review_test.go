@@ -0,0 +1,77 @@
+package steps
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReviewGate(t *testing.T) {
+	tmpDir := t.TempDir()
+	versionDir := filepath.Join(tmpDir, "artifacts", "4.12.0-x86_64")
+	outputDir := filepath.Join(versionDir, "_output")
+
+	os.MkdirAll(filepath.Join(versionDir, "manifests"), 0755)
+	os.MkdirAll(filepath.Join(versionDir, "openshift"), 0755)
+	os.MkdirAll(filepath.Join(outputDir, "manifests"), 0755)
+	os.WriteFile(filepath.Join(versionDir, "manifests", "cluster-config.yaml"), []byte("kind: ConfigMap\n"), 0644)
+	os.WriteFile(filepath.Join(versionDir, "openshift", "99-extra.yaml"), []byte("kind: MachineConfig\n"), 0644)
+	os.WriteFile(filepath.Join(versionDir, injectedManifestsFile), []byte("openshift/99-extra.yaml"), 0644)
+	os.WriteFile(filepath.Join(outputDir, "manifests", "openshift-image-registry-credentials.yaml"), []byte("kind: Secret\n"), 0644)
+
+	manifests, err := CollectManifests(versionDir, outputDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	origins := map[string]string{}
+	for _, m := range manifests {
+		origins[m.Path] = m.Origin
+	}
+	expected := map[string]string{
+		"manifests/cluster-config.yaml":                               OriginGenerated,
+		"openshift/99-extra.yaml":                                     OriginInjected,
+		"_output/manifests/openshift-image-registry-credentials.yaml": OriginCcoctl,
+	}
+	for path, origin := range expected {
+		if origins[path] != origin {
+			t.Errorf("Expected %s to be %s, got %q", path, origin, origins[path])
+		}
+	}
+
+	// First review: view a manifest, then approve
+	var out bytes.Buffer
+	gate := &ReviewGate{VersionDir: versionDir, OutputDir: outputDir, In: strings.NewReader("2\na\nalice\n"), Out: &out}
+	approved, err := gate.Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !approved {
+		t.Error("Expected manifests to be approved")
+	}
+	if !strings.Contains(out.String(), "kind: MachineConfig") && !strings.Contains(out.String(), "kind: ConfigMap") {
+		t.Errorf("Expected manifest content to be shown, got:\n%s", out.String())
+	}
+
+	// Second review sees the change and rejects it
+	os.WriteFile(filepath.Join(versionDir, "manifests", "cluster-config.yaml"), []byte("kind: ConfigMap\ndata: {}\n"), 0644)
+	out.Reset()
+	gate.In = strings.NewReader("r\nbob\n")
+	approved, err = gate.Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if approved {
+		t.Error("Expected manifests to be rejected")
+	}
+	if !strings.Contains(out.String(), "~ manifests/cluster-config.yaml") {
+		t.Errorf("Expected changed manifest in diff, got:\n%s", out.String())
+	}
+
+	auditLog, _ := os.ReadFile(filepath.Join(versionDir, ReviewLogFile))
+	if !strings.Contains(string(auditLog), `"reviewer":"alice","decision":"approved"`) ||
+		!strings.Contains(string(auditLog), `"reviewer":"bob","decision":"rejected"`) {
+		t.Errorf("Unexpected audit log:\n%s", auditLog)
+	}
+}
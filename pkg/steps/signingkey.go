@@ -0,0 +1,101 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+// Key pair files written by ccoctl aws create-key-pair
+const (
+	signerPrivateKeyFile = "serviceaccount-signer.private"
+	signerPublicKeyFile  = "serviceaccount-signer.public"
+)
+
+// installSigningKey puts an existing key pair where ccoctl aws create-key-pair
+// would have written a new one
+func installSigningKey(key *config.SigningKey, outputDir string) error {
+	tlsDir := filepath.Join(outputDir, "tls")
+	if err := util.EnsureDir(tlsDir); err != nil {
+		return err
+	}
+
+	copies := []struct {
+		src, dst string
+		mode     os.FileMode
+	}{
+		{key.PrivateKey, filepath.Join(outputDir, signerPrivateKeyFile), 0600},
+		{key.PublicKey, filepath.Join(outputDir, signerPublicKeyFile), 0644},
+		{key.PrivateKey, filepath.Join(tlsDir, boundSigningKeyFile), 0600},
+	}
+	for _, c := range copies {
+		content, err := os.ReadFile(c.src)
+		if err != nil {
+			return fmt.Errorf("failed to read signing key: %w", err)
+		}
+		if err := os.WriteFile(c.dst, content, c.mode); err != nil {
+			return err
+		}
+		if err := os.Chmod(c.dst, c.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveSigningKey copies the key pair generated in Step 7 to destDir, which
+// must be outside the workspace. Existing files are never overwritten.
+func ArchiveSigningKey(outputDir, destDir string) ([]string, error) {
+	workspace, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dest, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(workspace, dest); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is inside the workspace %s, choose a directory outside of it", dest, workspace)
+	}
+
+	privateKey := filepath.Join(outputDir, signerPrivateKeyFile)
+	if !util.FileExists(privateKey) {
+		return nil, fmt.Errorf("no signing key found at %s, Step 7 has not generated one", privateKey)
+	}
+
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return nil, err
+	}
+
+	var archived []string
+	for _, file := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{signerPrivateKeyFile, 0600},
+		{signerPublicKeyFile, 0644},
+	} {
+		content, err := os.ReadFile(filepath.Join(outputDir, file.name))
+		if err != nil {
+			return archived, err
+		}
+		path := filepath.Join(dest, file.name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, file.mode)
+		if err != nil {
+			return archived, fmt.Errorf("failed to archive %s: %w", file.name, err)
+		}
+		_, err = f.Write(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return archived, err
+		}
+		archived = append(archived, path)
+	}
+	return archived, nil
+}
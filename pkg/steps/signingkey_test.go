@@ -0,0 +1,90 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestStep7ReusesSigningKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	os.MkdirAll("keys", 0700)
+	os.WriteFile("keys/signer.private", []byte("private"), 0600)
+	os.WriteFile("keys/signer.public", []byte("public"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+		SigningKey:   &config.SigningKey{PrivateKey: "keys/signer.private", PublicKey: "keys/signer.public"},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	createIdentityProvider := "artifacts/4.12.0-x86_64/bin/ccoctl aws create-identity-provider --name test-cluster --region us-east-2 --public-key-file keys/signer.public --output-dir _output"
+	executor.SetOutput(createIdentityProvider, "Identity Provider created with ARN: "+testProviderARN)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if executor.WasExecutedContaining("create-key-pair") {
+		t.Error("create-key-pair should not run with a configured signing key")
+	}
+	if !executor.WasExecuted(createIdentityProvider) {
+		t.Errorf("Expected identity provider to use the configured public key, got %v", executor.Commands)
+	}
+	info, err := os.Stat(filepath.Join("_output", "tls", boundSigningKeyFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected private key in tls/ with mode 0600, got %v (%v)", info, err)
+	}
+}
+
+func TestArchiveSigningKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	workspace := filepath.Join(tmpDir, "workspace")
+	os.MkdirAll(filepath.Join(workspace, "_output"), 0755)
+	originalWd, _ := os.Getwd()
+	os.Chdir(workspace)
+	defer os.Chdir(originalWd)
+
+	if _, err := ArchiveSigningKey("_output", filepath.Join(tmpDir, "archive")); err == nil {
+		t.Error("Expected error when no key was generated")
+	}
+
+	os.WriteFile(filepath.Join("_output", signerPrivateKeyFile), []byte("private"), 0644)
+	os.WriteFile(filepath.Join("_output", signerPublicKeyFile), []byte("public"), 0644)
+
+	if _, err := ArchiveSigningKey("_output", "keys"); err == nil {
+		t.Error("Expected error for a destination inside the workspace")
+	}
+
+	archive := filepath.Join(tmpDir, "archive")
+	archived, err := ArchiveSigningKey("_output", archive)
+	if err != nil {
+		t.Fatalf("ArchiveSigningKey failed: %v", err)
+	}
+	if len(archived) != 2 {
+		t.Fatalf("Expected 2 archived files, got %v", archived)
+	}
+	info, err := os.Stat(filepath.Join(archive, signerPrivateKeyFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected archived private key with mode 0600, got %v (%v)", info, err)
+	}
+
+	if _, err := ArchiveSigningKey("_output", archive); err == nil {
+		t.Error("Expected error instead of overwriting an archived key")
+	}
+}
@@ -0,0 +1,91 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	machineRoleLabel     = "machine.openshift.io/cluster-api-machine-role"
+	interruptibleLabel   = "machine.openshift.io/interruptible-instance"
+	workerMachineSetGlob = "99_openshift-cluster-api_worker-machineset-*.yaml"
+)
+
+// applySpotMarketOptions adds spotMarketOptions to the worker MachineSets
+// generated in openshift/. Control plane machines are left alone.
+func applySpotMarketOptions(versionDir string, maxPrice string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(versionDir, "openshift", workerMachineSetGlob))
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []string
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if doc["kind"] != "MachineSet" {
+			continue
+		}
+
+		template := childMap(childMap(doc, "spec"), "template")
+		labels := childMap(childMap(template, "metadata"), "labels")
+		if role, ok := labels[machineRoleLabel]; ok && role != "worker" {
+			continue
+		}
+
+		spotOptions := map[string]interface{}{}
+		if maxPrice != "" {
+			spotOptions["maxPrice"] = maxPrice
+		}
+		providerValue := childMap(childMap(childMap(template, "spec"), "providerSpec"), "value")
+		providerValue["spotMarketOptions"] = spotOptions
+
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return nil, err
+		}
+		patched = append(patched, filepath.Base(path))
+	}
+
+	return patched, nil
+}
+
+// nonSpotWorkers parses `oc get nodes -o json` output and returns the worker
+// nodes that are not running on spot instances
+func nonSpotWorkers(nodesJSON string) ([]string, int, error) {
+	var nodes struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(nodesJSON)), &nodes); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse node list: %w", err)
+	}
+
+	var onDemand []string
+	for _, node := range nodes.Items {
+		if _, ok := node.Metadata.Labels[interruptibleLabel]; !ok {
+			onDemand = append(onDemand, node.Metadata.Name)
+		}
+	}
+
+	return onDemand, len(nodes.Items), nil
+}
@@ -0,0 +1,89 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+func TestStep6ConfiguresSpotWorkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Compute:      config.ComputeConfig{Spot: &config.SpotConfig{MaxPrice: "0.25"}},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	openshiftDir := filepath.Join("artifacts", "4.12.0-x86_64", "openshift")
+	os.MkdirAll(openshiftDir, 0755)
+	workerPath := filepath.Join(openshiftDir, "99_openshift-cluster-api_worker-machineset-0.yaml")
+	os.WriteFile(workerPath, []byte(`apiVersion: machine.openshift.io/v1beta1
+kind: MachineSet
+spec:
+  template:
+    metadata:
+      labels:
+        machine.openshift.io/cluster-api-machine-role: worker
+    spec:
+      providerSpec:
+        value:
+          instanceType: m5.xlarge
+`), 0644)
+	masterPath := filepath.Join(openshiftDir, "99_openshift-cluster-api_master-machines-0.yaml")
+	masterContent := "apiVersion: machine.openshift.io/v1beta1\nkind: Machine\n"
+	os.WriteFile(masterPath, []byte(masterContent), 0644)
+
+	step, err := NewStep6(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if !util.FileContains(workerPath, "spotMarketOptions:") || !util.FileContains(workerPath, `maxPrice: "0.25"`) {
+		content, _ := os.ReadFile(workerPath)
+		t.Errorf("Expected spotMarketOptions in worker MachineSet. Content: %s", string(content))
+	}
+	if content, _ := os.ReadFile(masterPath); string(content) != masterContent {
+		t.Error("Control plane manifests should not be modified")
+	}
+}
+
+func TestStep11VerifiesSpotWorkers(t *testing.T) {
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Compute:      config.ComputeConfig{Spot: &config.SpotConfig{}},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	executor.SetOutput("oc get secrets -n openshift-image-registry installer-cloud-credentials -o json", "role_arn")
+	executor.SetOutput("oc get nodes -l node-role.kubernetes.io/worker -o json", `{"items":[
+		{"metadata":{"name":"worker-a","labels":{"machine.openshift.io/interruptible-instance":""}}},
+		{"metadata":{"name":"worker-b","labels":{}}}
+	]}`)
+
+	step, err := NewStep11(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err == nil {
+		t.Error("Expected error when a worker is not a spot instance")
+	}
+
+	executor.SetOutput("oc get nodes -l node-role.kubernetes.io/worker -o json", `{"items":[
+		{"metadata":{"name":"worker-a","labels":{"machine.openshift.io/interruptible-instance":""}}}
+	]}`)
+	if err := step.Execute(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
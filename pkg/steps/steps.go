@@ -18,12 +18,24 @@ type Step interface {
 	Execute() error
 }
 
+// Detail is a line a step reports in the installation summary
+type Detail struct {
+	Title string
+	Line  string
+}
+
+// Reporter is implemented by steps that report details in the summary
+type Reporter interface {
+	Details() []Detail
+}
+
 // BaseStep contains common fields for all steps
 type BaseStep struct {
 	cfg         *config.Config
 	log         *logger.Logger
 	executor    util.CommandExecutor
 	versionArch string
+	details     []Detail
 }
 
 func newBaseStep(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (*BaseStep, error) {
@@ -40,6 +52,33 @@ func newBaseStep(cfg *config.Config, log *logger.Logger, executor util.CommandEx
 	}, nil
 }
 
+// Details returns the summary details reported by the step
+func (s *BaseStep) Details() []Detail {
+	return s.details
+}
+
+func (s *BaseStep) addDetail(title, line string) {
+	s.details = append(s.details, Detail{Title: title, Line: line})
+}
+
+// env returns the environment variables every command run by a step gets
+func (s *BaseStep) env() []string {
+	return s.cfg.Proxy.EnvVars()
+}
+
+// awsEnv returns env() plus the AWS credentials of a profile, the IAM or
+// the infra one
+func (s *BaseStep) awsEnv(profile string) []string {
+	env := s.env()
+	awsEnv, err := util.NewAWSCredentialProvider(profile).Env()
+	if err != nil {
+		s.log.Debug(err.Error())
+		s.log.Debug("Proceeding without setting AWS credentials from profile")
+		return env
+	}
+	return append(env, awsEnv...)
+}
+
 // Step1ExtractCredReqs extracts credentials requests from the release image
 type Step1ExtractCredReqs struct {
 	*BaseStep
@@ -72,7 +111,7 @@ func (s *Step1ExtractCredReqs) Execute() error {
 		s.cfg.ReleaseImage,
 	}
 
-	return util.RunCommand(s.executor, "oc", args...)
+	return util.RunCommandWithEnv(s.executor, s.env(), "oc", args...)
 }
 
 // Step2ExtractOpenshiftInstall extracts openshift-install binary
@@ -107,7 +146,7 @@ func (s *Step2ExtractOpenshiftInstall) Execute() error {
 		"--registry-config=" + s.cfg.PullSecretPath,
 		s.cfg.ReleaseImage,
 	}
-	if err := util.RunCommand(s.executor, "oc", args...); err != nil {
+	if err := util.RunCommandWithEnv(s.executor, s.env(), "oc", args...); err != nil {
 		return fmt.Errorf("failed to extract openshift-install: %w", err)
 	}
 
@@ -140,7 +179,7 @@ func (s *Step3ExtractCcoctl) Execute() error {
 
 	// Get CCO image
 	ccoImageArgs := []string{"adm", "release", "info", "--image-for=cloud-credential-operator", "--registry-config=" + s.cfg.PullSecretPath, s.cfg.ReleaseImage}
-	ccoImage, err := s.executor.Execute("oc", ccoImageArgs...)
+	ccoImage, err := s.executor.ExecuteWithEnv("oc", s.env(), ccoImageArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to get CCO image: %w", err)
 	}
@@ -155,7 +194,7 @@ func (s *Step3ExtractCcoctl) Execute() error {
 		"--file=/usr/bin/ccoctl",
 		"--registry-config=" + s.cfg.PullSecretPath,
 	}
-	if err := util.RunCommand(s.executor, "oc", extractArgs...); err != nil {
+	if err := util.RunCommandWithEnv(s.executor, s.env(), "oc", extractArgs...); err != nil {
 		return fmt.Errorf("failed to extract ccoctl: %w", err)
 	}
 
@@ -201,9 +240,9 @@ func (s *Step4CreateConfig) Execute() error {
 	s.log.Info("Starting interactive install-config creation...")
 	s.log.Info("Please answer the prompts from openshift-install:")
 
-	// Note: We don't pass AWS credentials here because it breaks interactive mode
-	// The user should have AWS credentials already configured via AWS CLI or environment
-	return s.executor.ExecuteInteractive(installBin, args...)
+	// openshift-install lists the regions, hosted zones and base domains of
+	// the account, so it needs the profile credentials and the proxy settings
+	return s.executor.ExecuteInteractiveWithEnv(installBin, s.awsEnv(s.cfg.InfraProfile()), args...)
 }
 
 // Step5SetCredentialsMode appends credentialsMode: Manual to install-config.yaml
@@ -242,45 +281,49 @@ func (s *Step5SetCredentialsMode) Execute() error {
 		doc["credentialsMode"] = "Manual"
 	}
 
-	// Helper to ensure platform.aws.type is set in a machine pool-like object
+	// Cluster-wide proxy and the CA bundle needed to trust it
+	if s.cfg.Proxy.IsSet() {
+		if err := s.setProxy(doc, content); err != nil {
+			return err
+		}
+	}
+	if s.cfg.TrustBundle != "" {
+		bundle, err := os.ReadFile(s.cfg.TrustBundle)
+		if err != nil {
+			return fmt.Errorf("failed to read trust bundle: %w", err)
+		}
+		doc["additionalTrustBundle"] = string(bundle)
+	}
+
+	// Ensure platform.aws.type is set in every machine pool
 	desiredType := s.cfg.InstanceType
 	if strings.TrimSpace(desiredType) == "" {
 		desiredType = "m5.4xlarge"
 	}
 
-	ensurePoolType := func(pool map[string]interface{}) {
-		platform, ok := pool["platform"].(map[string]interface{})
-		if !ok {
-			platform = map[string]interface{}{}
-			pool["platform"] = platform
-		}
-		aws, ok := platform["aws"].(map[string]interface{})
-		if !ok {
-			aws = map[string]interface{}{}
-			platform["aws"] = aws
-		}
+	for _, pool := range machinePools(doc) {
+		aws := childMap(childMap(pool, "platform"), "aws")
 		if _, ok := aws["type"]; !ok || aws["type"] == "" {
 			aws["type"] = desiredType
 		}
 	}
 
-	// controlPlane
-	if cpRaw, ok := doc["controlPlane"]; ok {
-		if cp, ok := cpRaw.(map[string]interface{}); ok {
-			ensurePoolType(cp)
+	// Install-config settings of the hardening profile
+	applyInstallConfigHardening(s.cfg, doc)
+
+	// Tags for every AWS resource the installer creates
+	if len(s.cfg.Tags) > 0 {
+		userTags := childMap(childMap(childMap(doc, "platform"), "aws"), "userTags")
+		for key, value := range s.cfg.Tags {
+			userTags[key] = value
 		}
 	}
 
-	// compute (list of pools)
-	if compsRaw, ok := doc["compute"]; ok {
-		if comps, ok := compsRaw.([]interface{}); ok {
-			for i := range comps {
-				if pool, ok := comps[i].(map[string]interface{}); ok {
-					ensurePoolType(pool)
-				}
-			}
-			// assign back in case underlying slice was modified
-			doc["compute"] = comps
+	// The records of a hosted zone in another account are managed with a role
+	// assumed there, the one of the DNS profile
+	if s.cfg.AwsProfiles.DNS != "" {
+		if err := s.setHostedZoneRole(doc, content); err != nil {
+			return err
 		}
 	}
 
@@ -292,10 +335,94 @@ func (s *Step5SetCredentialsMode) Execute() error {
 	if err := os.WriteFile(configPath, out, 0644); err != nil {
 		return fmt.Errorf("failed to write install-config.yaml: %w", err)
 	}
+	s.reportHardening(5)
+
+	return nil
+}
+
+// setHostedZoneRole writes the role of the DNS profile to install-config.yaml.
+// openshift-install only accepts it together with the hosted zone, in an
+// existing VPC; a missing hostedZone is looked up with the DNS profile.
+func (s *Step5SetCredentialsMode) setHostedZoneRole(doc map[string]interface{}, content []byte) error {
+	roleARN, err := util.AWSProfileRoleARN(s.cfg.AwsProfiles.DNS)
+	if err != nil {
+		return fmt.Errorf("the dns profile must assume a role in the account of the hosted zone: %w", err)
+	}
+
+	var installConfig util.InstallConfig
+	if err := yaml.Unmarshal(content, &installConfig); err != nil {
+		return fmt.Errorf("failed to parse install-config.yaml: %w", err)
+	}
+	configPath := util.GetInstallConfigPath(s.versionArch)
+	if len(installConfig.Platform.AWS.Subnets) == 0 {
+		return fmt.Errorf("the hosted zone role of the dns profile needs an existing VPC: add platform.aws.subnets to %s and rerun with --start-from-step=5", configPath)
+	}
+
+	aws := childMap(childMap(doc, "platform"), "aws")
+	if installConfig.Platform.AWS.HostedZone == "" {
+		zoneID, err := util.FindPrivateHostedZone(s.executor, s.awsEnv(s.cfg.AwsProfiles.DNS), installConfig.BaseDomain)
+		if err != nil {
+			return fmt.Errorf("platform.aws.hostedZone is not set and looking it up with the dns profile failed: %w; set it in %s and rerun with --start-from-step=5", err, configPath)
+		}
+		s.log.Info(fmt.Sprintf("Using the private hosted zone %s of %s", zoneID, installConfig.BaseDomain))
+		aws["hostedZone"] = zoneID
+	}
+	aws["hostedZoneRole"] = roleARN
+	return nil
+}
+
+// setProxy writes the proxy stanza to install-config.yaml after checking that
+// noProxy covers the networks declared in it
+func (s *Step5SetCredentialsMode) setProxy(doc map[string]interface{}, content []byte) error {
+	var installConfig util.InstallConfig
+	if err := yaml.Unmarshal(content, &installConfig); err != nil {
+		return fmt.Errorf("failed to parse install-config.yaml: %w", err)
+	}
+	if err := config.ValidateNoProxy(s.cfg.Proxy.NoProxy, installConfig.NetworkCIDRs()); err != nil {
+		return err
+	}
+
+	proxy := map[string]interface{}{}
+	if s.cfg.Proxy.HTTPProxy != "" {
+		proxy["httpProxy"] = s.cfg.Proxy.HTTPProxy
+	}
+	if s.cfg.Proxy.HTTPSProxy != "" {
+		proxy["httpsProxy"] = s.cfg.Proxy.HTTPSProxy
+	}
+	if s.cfg.Proxy.NoProxy != "" {
+		proxy["noProxy"] = s.cfg.Proxy.NoProxy
+	}
+	doc["proxy"] = proxy
 
 	return nil
 }
 
+// machinePools returns the controlPlane and compute machine pools of an install-config document
+func machinePools(doc map[string]interface{}) []map[string]interface{} {
+	var pools []map[string]interface{}
+	if cp, ok := doc["controlPlane"].(map[string]interface{}); ok {
+		pools = append(pools, cp)
+	}
+	if comps, ok := doc["compute"].([]interface{}); ok {
+		for i := range comps {
+			if pool, ok := comps[i].(map[string]interface{}); ok {
+				pools = append(pools, pool)
+			}
+		}
+	}
+	return pools
+}
+
+// childMap returns parent[key] as a map, creating it if it is missing
+func childMap(parent map[string]interface{}, key string) map[string]interface{} {
+	child, ok := parent[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		parent[key] = child
+	}
+	return child
+}
+
 // Step6CreateManifests runs openshift-install create manifests
 type Step6CreateManifests struct {
 	*BaseStep
@@ -318,7 +445,56 @@ func (s *Step6CreateManifests) Execute() error {
 	installBin := util.GetBinaryPath(s.versionArch, "openshift-install")
 	args := []string{"create", "manifests", "--dir", versionDir}
 
-	return util.RunCommand(s.executor, installBin, args...)
+	// Injected again below if still configured
+	if err := removeInjectedManifests(versionDir); err != nil {
+		return err
+	}
+
+	if err := util.RunCommandWithEnv(s.executor, s.env(), installBin, args...); err != nil {
+		return err
+	}
+
+	// Before any edit, so the patches start from what was just generated
+	if err := refreshManifestOriginals(versionDir, s.cfg.ManifestPatches); err != nil {
+		return err
+	}
+
+	if hardeningEnabled(s.cfg, HardeningEtcdEncryption) {
+		if err := applyEtcdEncryption(versionDir); err != nil {
+			return fmt.Errorf("failed to enable etcd encryption: %w", err)
+		}
+	}
+
+	if s.cfg.Compute.Spot != nil {
+		patched, err := applySpotMarketOptions(versionDir, s.cfg.Compute.Spot.MaxPrice)
+		if err != nil {
+			return fmt.Errorf("failed to configure spot instances: %w", err)
+		}
+		if len(patched) == 0 {
+			s.log.Info("WARNING: No worker MachineSet manifests found, spot instances not configured")
+		}
+		for _, name := range patched {
+			s.log.Debug(fmt.Sprintf("Configured spot instances in %s", name))
+		}
+	}
+
+	// User manifests and patches
+	if len(s.cfg.ExtraManifests) > 0 {
+		injected, err := injectExtraManifests(versionDir, s.cfg.ExtraManifests)
+		if err != nil {
+			return fmt.Errorf("failed to inject extra manifests: %w", err)
+		}
+		s.log.Info(fmt.Sprintf("Injected %d extra manifests", len(injected)))
+	}
+	if len(s.cfg.ManifestPatches) > 0 {
+		if err := applyManifestPatches(versionDir, s.cfg.ManifestPatches); err != nil {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("Applied %d manifest patches", len(s.cfg.ManifestPatches)))
+	}
+	s.reportHardening(6)
+
+	return nil
 }
 
 // Additional steps will follow the same pattern...
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
 	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
@@ -29,20 +30,84 @@ func (s *Step7CreateAWSResources) Name() string {
 }
 
 func (s *Step7CreateAWSResources) Execute() error {
-	ccoctlBin := util.GetBinaryPath(s.versionArch, "ccoctl")
-	credreqsPath := util.GetCredReqsPath(s.versionArch)
-
 	// Cluster name and region should be available from config
 	// (loaded after Step 4 from install-config.yaml if not specified)
 	if s.cfg.ClusterName == "" || s.cfg.AwsRegion == "" {
 		return fmt.Errorf("cluster name and AWS region are required. They should have been loaded from install-config.yaml after Step 4")
 	}
 
+	// IAM roles and OIDC issuer managed outside the installer
+	if s.cfg.BYOIAM != nil {
+		s.log.Info("Rendering credentials for the provided IAM roles (skipping ccoctl)...")
+		return renderBYOIAM(s.cfg.BYOIAM, util.GetCredReqsPath(s.versionArch), s.cfg.OutputDir)
+	}
+
+	state, err := loadCcoctlState(s.cfg.OutputDir)
+	if err != nil {
+		return err
+	}
+
+	run := map[string]func(*ccoctlState) error{
+		SubStepCreateKeyPair:          s.createKeyPair,
+		SubStepCreateIdentityProvider: s.createIdentityProvider,
+		SubStepCreateIAMRoles:         s.createIAMRoles,
+		SubStepPermissionsBoundary:    s.attachPermissionsBoundary,
+		SubStepTagResources:           s.tagResources,
+	}
+
+	for _, subStep := range ccoctlSubSteps {
+		if state.isDone(subStep) {
+			s.log.Info(fmt.Sprintf("⏭  Skipping %s (already completed)", subStepTitle(subStep)))
+			continue
+		}
+
+		s.log.Info(fmt.Sprintf("Running %s...", subStepTitle(subStep)))
+		if err := run[subStep](state); err != nil {
+			return fmt.Errorf("%s failed: %w", subStepTitle(subStep), err)
+		}
+
+		state.markDone(subStep)
+		if err := state.save(s.cfg.OutputDir); err != nil {
+			return fmt.Errorf("failed to save ccoctl state: %w", err)
+		}
+
+		// Record the resources as soon as they exist, cleanup needs them most
+		// when a later sub-step fails
+		if subStep == SubStepCreateIdentityProvider || subStep == SubStepCreateIAMRoles {
+			if err := s.recordInventory(state); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.recordInventory(state); err != nil {
+		return err
+	}
+	s.reportHardening(7)
+	return nil
+}
+
+func (s *Step7CreateAWSResources) createKeyPair(state *ccoctlState) error {
+	if s.cfg.SigningKey != nil {
+		s.log.Info("Reusing the configured service-account signing key pair")
+		return installSigningKey(s.cfg.SigningKey, s.cfg.OutputDir)
+	}
+
+	ccoctlBin := util.GetBinaryPath(s.versionArch, "ccoctl")
+	args := []string{
+		"aws", "create-key-pair",
+		"--output-dir", s.cfg.OutputDir,
+	}
+	return util.RunCommandWithEnv(s.executor, s.env(), ccoctlBin, args...)
+}
+
+func (s *Step7CreateAWSResources) createIdentityProvider(state *ccoctlState) error {
+	ccoctlBin := util.GetBinaryPath(s.versionArch, "ccoctl")
 	args := []string{
-		"aws", "create-all",
+		"aws", "create-identity-provider",
 		"--name", s.cfg.ClusterName,
 		"--region", s.cfg.AwsRegion,
-		"--credentials-requests-dir", credreqsPath,
+		"--public-key-file", s.publicKeyFile(),
 		"--output-dir", s.cfg.OutputDir,
 	}
 
@@ -50,15 +115,58 @@ func (s *Step7CreateAWSResources) Execute() error {
 		args = append(args, "--create-private-s3-bucket")
 	}
 
-	// Get AWS credentials from profile and set as environment variables
-	awsEnv, err := util.GetAWSEnvVars(s.cfg.AwsProfile)
+	env := s.awsEnv(s.cfg.IAMProfile())
+	output, err := util.RunCommandWithEnvOutput(s.executor, env, ccoctlBin, args...)
+	if err != nil {
+		return err
+	}
+
+	// The provider exists by now: when the output does not name it, look it
+	// up by its issuer rather than colliding with it on a rerun
+	arn, err := parseIdentityProviderARN(output)
 	if err != nil {
-		s.log.Debug(fmt.Sprintf("Could not read AWS credentials from profile '%s': %v", s.cfg.AwsProfile, err))
-		s.log.Debug("Proceeding without setting AWS credentials from profile")
-		return util.RunCommand(s.executor, ccoctlBin, args...)
+		installation := &util.Installation{
+			ClusterName: s.cfg.ClusterName,
+			Region:      s.cfg.AwsRegion,
+			Issuer:      util.CcoctlIssuer(s.cfg.OutputDir),
+		}
+		found, lookupErr := util.FindOIDCProviderARN(s.executor, env, installation)
+		if lookupErr != nil {
+			return fmt.Errorf("%w, and looking it up failed: %v", err, lookupErr)
+		}
+		arn = found
+	}
+	s.log.Debug(fmt.Sprintf("Identity provider ARN: %s", arn))
+	state.IdentityProviderARN = arn
+	state.CloudFrontDistributionID = parseCloudFrontDistributionID(output)
+
+	return nil
+}
+
+// publicKeyFile returns the public key published by the identity provider
+func (s *Step7CreateAWSResources) publicKeyFile() string {
+	if s.cfg.SigningKey != nil {
+		return s.cfg.SigningKey.PublicKey
+	}
+	return filepath.Join(s.cfg.OutputDir, signerPublicKeyFile)
+}
+
+func (s *Step7CreateAWSResources) createIAMRoles(state *ccoctlState) error {
+	if state.IdentityProviderARN == "" {
+		return fmt.Errorf("identity provider ARN is unknown, re-run %s", SubStepCreateIdentityProvider)
+	}
+
+	ccoctlBin := util.GetBinaryPath(s.versionArch, "ccoctl")
+	args := []string{
+		"aws", "create-iam-roles",
+		"--name", s.cfg.ClusterName,
+		"--region", s.cfg.AwsRegion,
+		"--credentials-requests-dir", util.GetCredReqsPath(s.versionArch),
+		"--identity-provider-arn", state.IdentityProviderARN,
+		"--output-dir", s.cfg.OutputDir,
 	}
 
-	return util.RunCommandWithEnv(s.executor, awsEnv, ccoctlBin, args...)
+	return util.RunCommandWithEnv(s.executor, s.awsEnv(s.cfg.IAMProfile()), ccoctlBin, args...)
 }
 
 // Step8CopyManifests copies manifests from _output to manifests/
@@ -139,17 +247,14 @@ func (s *Step10DeployCluster) Execute() error {
 	installBin := util.GetBinaryPath(s.versionArch, "openshift-install")
 	args := []string{"create", "cluster", "--dir", versionDir, "--log-level=debug"}
 
-	// Get AWS credentials from profile and set as environment variables
-	awsEnv, err := util.GetAWSEnvVars(s.cfg.AwsProfile)
-	if err != nil {
-		s.log.Debug(fmt.Sprintf("Could not read AWS credentials from profile '%s': %v", s.cfg.AwsProfile, err))
-		s.log.Debug("Proceeding without setting AWS credentials from profile")
-		// Use interactive execution to stream output in real-time
-		return s.executor.ExecuteInteractive(installBin, args...)
-	}
-
 	// Use interactive execution with env vars to stream output in real-time
-	return s.executor.ExecuteInteractiveWithEnv(installBin, awsEnv, args...)
+	err := s.executor.ExecuteInteractiveWithEnv(installBin, s.awsEnv(s.cfg.InfraProfile()), args...)
+
+	// The infrastructure exists as soon as metadata.json is written, even if the deployment failed
+	if recordErr := s.recordInventory(versionDir); recordErr != nil {
+		s.log.Debug(fmt.Sprintf("Could not record infraID in %s: %v", util.InventoryFile, recordErr))
+	}
+	return err
 }
 
 // Step11Verify performs post-install verification
@@ -171,7 +276,7 @@ func (s *Step11Verify) Name() string {
 
 func (s *Step11Verify) Execute() error {
 	// Check 1: Root credentials should not exist
-	_, err := s.executor.Execute("oc", "get", "secrets", "-n", "kube-system", "aws-creds")
+	_, err := s.executor.ExecuteWithEnv("oc", s.env(), "get", "secrets", "-n", "kube-system", "aws-creds")
 	if err == nil {
 		s.log.Error("WARNING: Root credentials secret exists (expected it to not exist)")
 	} else {
@@ -179,7 +284,7 @@ func (s *Step11Verify) Execute() error {
 	}
 
 	// Check 2: Components should use IAM roles
-	output, err := s.executor.Execute("oc", "get", "secrets", "-n", "openshift-image-registry",
+	output, err := s.executor.ExecuteWithEnv("oc", s.env(), "get", "secrets", "-n", "openshift-image-registry",
 		"installer-cloud-credentials", "-o", "json")
 	if err != nil {
 		return fmt.Errorf("failed to check IAM role usage: %w", err)
@@ -191,6 +296,26 @@ func (s *Step11Verify) Execute() error {
 		s.log.Error("WARNING: Components may not be using IAM roles correctly")
 	}
 
+	// Check 3: Workers should run on spot instances when requested
+	if s.cfg.Compute.Spot != nil {
+		output, err := s.executor.ExecuteWithEnv("oc", s.env(), "get", "nodes",
+			"-l", "node-role.kubernetes.io/worker", "-o", "json")
+		if err != nil {
+			return fmt.Errorf("failed to list worker nodes: %w", err)
+		}
+		onDemand, total, err := nonSpotWorkers(output)
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			return fmt.Errorf("no worker nodes found to verify spot instances")
+		}
+		if len(onDemand) > 0 {
+			return fmt.Errorf("worker nodes not running on spot instances: %s", strings.Join(onDemand, ", "))
+		}
+		s.log.Info(fmt.Sprintf("✓ All %d worker nodes are running on spot instances", total))
+	}
+
 	return nil
 }
 
@@ -3,6 +3,7 @@ package steps
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
@@ -177,3 +178,182 @@ func TestStep5CreateManifests(t *testing.T) {
 		t.Error("Expected 'create manifests' command")
 	}
 }
+
+func TestStep5SetsProxy(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	os.WriteFile("ca.pem", []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		Proxy: config.ProxyConfig{
+			HTTPSProxy: "http://proxy.example.com:3128",
+			NoProxy:    "10.0.0.0/16,10.128.0.0/14,172.30.0.0/16",
+		},
+		TrustBundle: "ca.pem",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	configPath := util.GetInstallConfigPath("4.12.0-x86_64")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte(`apiVersion: v1
+networking:
+  machineNetwork:
+  - cidr: 10.0.0.0/16
+  clusterNetwork:
+  - cidr: 10.128.0.0/14
+  serviceNetwork:
+  - 172.30.0.0/16
+`), 0644)
+
+	step, err := NewStep5(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if !util.FileContains(configPath, "httpsProxy: http://proxy.example.com:3128") {
+		t.Error("Expected httpsProxy in install-config.yaml")
+	}
+	if !util.FileContains(configPath, "additionalTrustBundle:") {
+		t.Error("Expected additionalTrustBundle in install-config.yaml")
+	}
+
+	// Tags go to platform.aws.userTags
+	cfg.Tags = map[string]string{"cost-center": "1234"}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !util.FileContains(configPath, "cost-center: \"1234\"") {
+		t.Error("Expected tags in platform.aws.userTags of install-config.yaml")
+	}
+
+	// A noProxy that misses the service network must be rejected
+	cfg.Proxy.NoProxy = "10.0.0.0/8"
+	if err := step.Execute(); err == nil {
+		t.Error("Expected error when noProxy does not cover the cluster networks")
+	}
+}
+
+func TestStepsPassProxyEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+		Proxy: config.ProxyConfig{
+			HTTPSProxy: "http://proxy.example.com:3128",
+			NoProxy:    "10.0.0.0/16,.example.com",
+		},
+	}
+	// The ccoctl output without any sub-step done
+	writeCcoctlOutput(t, cfg.OutputDir)
+	os.Remove(filepath.Join(cfg.OutputDir, util.CcoctlStateFile))
+	versionDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(versionDir, 0755)
+	os.WriteFile(filepath.Join(versionDir, "metadata.json"), []byte(`{"clusterName":"test-cluster","infraID":"test-cluster-x7k2p"}`), 0644)
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	executor.SetOutput(testCreateIdentityProv, "2024/01/01 Identity Provider created with ARN: "+testProviderARN+"\n")
+
+	step1, _ := NewStep1(cfg, log, executor)
+	step7, _ := NewStep7(cfg, log, executor)
+	step10, _ := NewStep10(cfg, log, executor)
+	for _, step := range []Step{step1, step7, step10} {
+		if err := step.Execute(); err != nil {
+			t.Fatalf("%s failed: %v", step.Name(), err)
+		}
+	}
+
+	for _, command := range []string{"oc adm release extract", "ccoctl aws", "openshift-install create cluster"} {
+		envs := executor.EnvContaining(command)
+		if len(envs) == 0 {
+			t.Errorf("Expected %s to run", command)
+		}
+		for _, env := range envs {
+			joined := strings.Join(env, "\n")
+			for _, want := range []string{"HTTPS_PROXY=http://proxy.example.com:3128", "https_proxy=http://proxy.example.com:3128", "NO_PROXY=10.0.0.0/16,.example.com"} {
+				if !strings.Contains(joined, want) {
+					t.Errorf("Expected %s in the environment of %s, got %v", want, command, env)
+				}
+			}
+		}
+	}
+}
+
+func TestStep5SetsHostedZoneRole(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	os.WriteFile("aws-config", []byte(`[profile network]
+role_arn = arn:aws:iam::333333333333:role/route53-records
+source_profile = default
+
+[profile plain]
+region = us-east-2
+`), 0600)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(tmpDir, "aws-config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(tmpDir, "aws-credentials"))
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		AwsProfiles:  config.AWSProfilesConfig{DNS: "network"},
+	}
+	configPath := util.GetInstallConfigPath("4.12.0-x86_64")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws route53 list-hosted-zones-by-name --dns-name example.com --output json", `{"HostedZones": [
+		{"Id": "/hostedzone/Z0PUBLIC", "Name": "example.com.", "Config": {"PrivateZone": false}},
+		{"Id": "/hostedzone/Z0SHARED", "Name": "example.com.", "Config": {"PrivateZone": true}}
+	]}`)
+	step, err := NewStep5(cfg, logger.New(logger.LevelQuiet, nil), executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+
+	// A hosted zone role is only accepted in an existing VPC
+	os.WriteFile(configPath, []byte("apiVersion: v1\nbaseDomain: example.com\nplatform:\n  aws:\n    region: us-east-2\n"), 0644)
+	if err := step.Execute(); err == nil || !strings.Contains(err.Error(), "platform.aws.subnets") {
+		t.Errorf("Expected an error asking for subnets, got %v", err)
+	}
+
+	// The private zone is looked up with the dns profile
+	os.WriteFile(configPath, []byte("apiVersion: v1\nbaseDomain: example.com\nplatform:\n  aws:\n    region: us-east-2\n    subnets: [subnet-0abc]\n"), 0644)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !util.FileContains(configPath, "hostedZoneRole: arn:aws:iam::333333333333:role/route53-records") ||
+		!util.FileContains(configPath, "hostedZone: Z0SHARED") {
+		content, _ := os.ReadFile(configPath)
+		t.Errorf("Expected the shared zone and the role of the dns profile, got:\n%s", content)
+	}
+
+	// A configured hosted zone is kept
+	os.WriteFile(configPath, []byte("apiVersion: v1\nbaseDomain: example.com\nplatform:\n  aws:\n    region: us-east-2\n    subnets: [subnet-0abc]\n    hostedZone: Z0OTHER\n"), 0644)
+	executor.Commands = nil
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !util.FileContains(configPath, "hostedZone: Z0OTHER") || len(executor.Commands) != 0 {
+		t.Errorf("Expected the configured hosted zone without lookup, got %v", executor.Commands)
+	}
+
+	// The dns profile must assume a role
+	cfg.AwsProfiles.DNS = "plain"
+	if err := step.Execute(); err == nil {
+		t.Error("Expected an error for a dns profile without role_arn")
+	}
+}
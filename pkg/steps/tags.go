@@ -0,0 +1,144 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const tagsDetailTitle = "AWS tags"
+
+// Kinds of resources created by ccoctl
+const (
+	resourceIAMRole      = "IAM role"
+	resourceOIDCProvider = "OIDC provider"
+	resourceS3Bucket     = "S3 bucket"
+)
+
+// awsResource is a resource created by ccoctl, identified by name or ARN
+type awsResource struct {
+	Kind string
+	ID   string
+}
+
+// ccoctlResources lists the IAM roles, OIDC provider and S3 bucket created in Step 7
+func (s *Step7CreateAWSResources) ccoctlResources(state *ccoctlState) ([]awsResource, error) {
+	roleARNs, err := util.CcoctlRoleARNs(filepath.Join(s.cfg.OutputDir, "manifests"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role ARNs from ccoctl manifests: %w", err)
+	}
+	if len(roleARNs) == 0 {
+		return nil, fmt.Errorf("no IAM role found in %s", filepath.Join(s.cfg.OutputDir, "manifests"))
+	}
+
+	var resources []awsResource
+	for _, arn := range roleARNs {
+		resources = append(resources, awsResource{Kind: resourceIAMRole, ID: util.RoleNameFromARN(arn)})
+	}
+	if state.IdentityProviderARN != "" {
+		resources = append(resources, awsResource{Kind: resourceOIDCProvider, ID: state.IdentityProviderARN})
+	}
+	resources = append(resources, awsResource{Kind: resourceS3Bucket, ID: util.OIDCBucketName(s.cfg.ClusterName)})
+	return resources, nil
+}
+
+// tagResources applies the configured tags to the resources created by
+// ccoctl, then reads them back to verify them
+func (s *Step7CreateAWSResources) tagResources(state *ccoctlState) error {
+	if len(s.cfg.Tags) == 0 {
+		return nil
+	}
+
+	resources, err := s.ccoctlResources(state)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, resource := range resources {
+		if err := s.tagResource(resource); err != nil {
+			s.log.Debug(err.Error())
+			failed = append(failed, fmt.Sprintf("%s %s", resource.Kind, resource.ID))
+			s.addDetail(tagsDetailTitle, fmt.Sprintf("✗ %s %s: %v", resource.Kind, resource.ID, err))
+			continue
+		}
+		s.addDetail(tagsDetailTitle, fmt.Sprintf("✓ %s %s (%d tags)", resource.Kind, resource.ID, len(s.cfg.Tags)))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("tags could not be applied to: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (s *Step7CreateAWSResources) tagResource(resource awsResource) error {
+	tags, err := json.Marshal(util.AWSTagList(s.cfg.Tags))
+	if err != nil {
+		return err
+	}
+
+	switch resource.Kind {
+	case resourceIAMRole:
+		_, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "tag-role", "--role-name", resource.ID, "--tags", string(tags))
+	case resourceOIDCProvider:
+		_, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "tag-open-id-connect-provider", "--open-id-connect-provider-arn", resource.ID, "--tags", string(tags))
+	case resourceS3Bucket:
+		err = s.tagBucket(resource.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	actual, err := s.resourceTags(resource)
+	if err != nil {
+		return fmt.Errorf("failed to verify tags: %w", err)
+	}
+	if missing := util.MissingTags(s.cfg.Tags, actual); len(missing) > 0 {
+		return fmt.Errorf("tags missing after tagging: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// tagBucket adds the tags to the bucket. put-bucket-tagging replaces the
+// whole tag set, so the tags set by ccoctl are merged in first.
+func (s *Step7CreateAWSResources) tagBucket(bucket string) error {
+	tags, err := s.resourceTags(awsResource{Kind: resourceS3Bucket, ID: bucket})
+	if err != nil {
+		return err
+	}
+	for key, value := range s.cfg.Tags {
+		tags[key] = value
+	}
+
+	tagging, err := json.Marshal(map[string][]util.AWSTag{"TagSet": util.AWSTagList(tags)})
+	if err != nil {
+		return err
+	}
+	_, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "s3api", "put-bucket-tagging", "--bucket", bucket, "--region", s.cfg.AwsRegion, "--tagging", string(tagging))
+	return err
+}
+
+// resourceTags reads the current tags of a resource
+func (s *Step7CreateAWSResources) resourceTags(resource awsResource) (map[string]string, error) {
+	var output string
+	var err error
+	switch resource.Kind {
+	case resourceIAMRole:
+		output, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "list-role-tags", "--role-name", resource.ID)
+	case resourceOIDCProvider:
+		output, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "iam", "list-open-id-connect-provider-tags", "--open-id-connect-provider-arn", resource.ID)
+	case resourceS3Bucket:
+		output, err = util.RunAWS(s.executor, s.awsEnv(s.cfg.IAMProfile()), "s3api", "get-bucket-tagging", "--bucket", resource.ID, "--region", s.cfg.AwsRegion)
+		// A bucket without tags is reported as an error
+		if err != nil && strings.Contains(output, "NoSuchTagSet") {
+			return map[string]string{}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return util.ParseAWSTags(output)
+}
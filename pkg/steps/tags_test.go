@@ -0,0 +1,115 @@
+package steps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/config"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/logger"
+	"gitlab.cee.redhat.com/clobrano/ccoctl-sso/pkg/util"
+)
+
+const (
+	testTagsJSON   = `[{"Key":"owner","Value":"team-a"}]`
+	testRoleTagged = `{"Tags":[{"Key":"owner","Value":"team-a"}]}`
+)
+
+// writeCcoctlOutput simulates the ccoctl sub-steps up to create-iam-roles
+func writeCcoctlOutput(t *testing.T, outputDir string) {
+	t.Helper()
+	os.MkdirAll(filepath.Join(outputDir, "manifests"), 0755)
+	os.WriteFile(filepath.Join(outputDir, "manifests", "openshift-ingress-operator-cloud-credentials-credentials.yaml"),
+		[]byte(credentialsSecret("openshift-ingress-operator", "cloud-credentials", "arn:aws:iam::123456789012:role/test-cluster-ingress")), 0600)
+	state := &ccoctlState{
+		Completed:           []string{SubStepCreateKeyPair, SubStepCreateIdentityProvider, SubStepCreateIAMRoles},
+		IdentityProviderARN: testProviderARN,
+	}
+	if err := state.save(outputDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStep7TagsResources(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+		Tags:         map[string]string{"owner": "team-a"},
+	}
+	writeCcoctlOutput(t, cfg.OutputDir)
+
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws iam list-role-tags --role-name test-cluster-ingress --output json", testRoleTagged)
+	executor.SetOutput("aws iam list-open-id-connect-provider-tags --open-id-connect-provider-arn "+testProviderARN+" --output json", testRoleTagged)
+	getBucketTags := "aws s3api get-bucket-tagging --bucket test-cluster-oidc --region us-east-2 --output json"
+	executor.SetOutput(getBucketTags, `{"TagSet":[{"Key":"kubernetes.io/cluster/test-cluster","Value":"owned"},{"Key":"owner","Value":"team-a"}]}`)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	for _, cmd := range []string{
+		"aws iam tag-role --role-name test-cluster-ingress --tags " + testTagsJSON + " --output json",
+		"aws iam tag-open-id-connect-provider --open-id-connect-provider-arn " + testProviderARN + " --tags " + testTagsJSON + " --output json",
+		// The tags set by ccoctl are kept
+		`aws s3api put-bucket-tagging --bucket test-cluster-oidc --region us-east-2 --tagging {"TagSet":[{"Key":"kubernetes.io/cluster/test-cluster","Value":"owned"},{"Key":"owner","Value":"team-a"}]} --output json`,
+	} {
+		if !executor.WasExecuted(cmd) {
+			t.Errorf("Expected command to be executed: %s", cmd)
+		}
+	}
+	if len(step.Details()) != 3 {
+		t.Errorf("Expected a summary detail per resource, got %v", step.Details())
+	}
+	if !CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should be complete")
+	}
+}
+
+func TestStep7TagVerificationFails(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		OutputDir:    "_output",
+		Tags:         map[string]string{"owner": "team-a"},
+	}
+	writeCcoctlOutput(t, cfg.OutputDir)
+
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws iam list-open-id-connect-provider-tags --open-id-connect-provider-arn "+testProviderARN+" --output json", testRoleTagged)
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket test-cluster-oidc --region us-east-2 --output json", `{"TagSet":[{"Key":"owner","Value":"team-a"}]}`)
+	// The role reports no tags after tagging
+	executor.SetOutput("aws iam list-role-tags --role-name test-cluster-ingress --output json", `{"Tags":[]}`)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	err = step.Execute()
+	if err == nil || !strings.Contains(err.Error(), "IAM role test-cluster-ingress") {
+		t.Errorf("Expected error naming the untagged role, got: %v", err)
+	}
+	if CcoctlCompleted(cfg.OutputDir) {
+		t.Error("Step 7 should not be complete when tagging failed")
+	}
+}
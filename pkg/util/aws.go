@@ -1,94 +1,84 @@
 package util
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
+	"time"
 )
 
-// AWSCredentials holds AWS credentials from the credentials file
+// AWSCredentials holds the AWS credentials resolved for a profile
 type AWSCredentials struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	SessionToken    string
+	// Expiration is zero for long-term keys
+	Expiration time.Time
+	// Source tells where the credentials come from
+	Source string
 }
 
-// ReadAWSCredentials reads AWS credentials from ~/.aws/credentials for a given profile
+// ReadAWSCredentials reads the static AWS credentials of a profile from the
+// shared credentials file (~/.aws/credentials or AWS_SHARED_CREDENTIALS_FILE)
 func ReadAWSCredentials(profile string) (*AWSCredentials, error) {
 	if profile == "" {
 		profile = "default"
 	}
 
-	homeDir, err := os.UserHomeDir()
+	credentialsPath, err := sharedCredentialsFile()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	credentialsPath := filepath.Join(homeDir, ".aws", "credentials")
-	file, err := os.Open(credentialsPath)
+	sections, err := readINIFile(credentialsPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open credentials file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
-
-	creds := &AWSCredentials{}
-	inTargetSection := false
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
-			continue
-		}
-
-		// Check for section header
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			sectionName := strings.Trim(line, "[]")
-			inTargetSection = (sectionName == profile)
-			continue
-		}
 
-		// If we're in the target section, read the credentials
-		if inTargetSection {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) != 2 {
-				continue
-			}
-
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			switch key {
-			case "aws_access_key_id":
-				creds.AccessKeyID = value
-			case "aws_secret_access_key":
-				creds.SecretAccessKey = value
-			case "aws_session_token":
-				creds.SessionToken = value
-			}
-		}
+	creds := staticCredentials(sections[profile])
+	// Validate that we found at least the required credentials
+	if creds == nil {
+		return nil, fmt.Errorf("profile '%s' not found or missing required credentials", profile)
 	}
+	return creds, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading credentials file: %w", err)
+// staticCredentials returns the long-term keys of a profile section, nil
+// when it has none
+func staticCredentials(keys map[string]string) *AWSCredentials {
+	if keys["aws_access_key_id"] == "" || keys["aws_secret_access_key"] == "" {
+		return nil
 	}
-
-	// Validate that we found at least the required credentials
-	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
-		return nil, fmt.Errorf("profile '%s' not found or missing required credentials", profile)
+	creds := &AWSCredentials{
+		AccessKeyID:     keys["aws_access_key_id"],
+		SecretAccessKey: keys["aws_secret_access_key"],
+		SessionToken:    keys["aws_session_token"],
+		Source:          CredentialSourceStatic,
+	}
+	// Tools writing session tokens to the profile (saml2aws, gimme-aws-creds,
+	// ...) record their expiry in one of these keys
+	for _, key := range []string{"aws_expiration", "x_security_token_expires", "aws_session_expiration"} {
+		if expiration, err := time.Parse(time.RFC3339, keys[key]); err == nil {
+			creds.Expiration = expiration
+			break
+		}
 	}
+	return creds
+}
 
-	return creds, nil
+// ExpiresWithin reports whether the credentials expire within d. Long-term
+// keys and session tokens of unknown expiry never do.
+func (c *AWSCredentials) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return !c.Expiration.IsZero() && c.Expiration.Before(now.Add(d))
+}
+
+// ExpiryUnknown reports whether the credentials are a session whose expiry
+// is not known
+func (c *AWSCredentials) ExpiryUnknown() bool {
+	return c.SessionToken != "" && c.Expiration.IsZero()
 }
 
 // GetAWSEnvVars returns environment variables for AWS credentials
 func GetAWSEnvVars(profile string) ([]string, error) {
-	creds, err := ReadAWSCredentials(profile)
+	creds, err := ResolveAWSCredentials(profile)
 	if err != nil {
 		return nil, err
 	}
@@ -105,32 +95,38 @@ func GetAWSEnvVars(profile string) ([]string, error) {
 	return envVars, nil
 }
 
-// ValidateAWSCredentials checks if AWS credentials are valid and not expired
-// by making a simple STS GetCallerIdentity API call
-func ValidateAWSCredentials(profile string) error {
-	// Try to get credentials for the profile
-	envVars, err := GetAWSEnvVars(profile)
+// ValidateAWSCredentials checks that the credentials of a profile are valid
+// and not expired with a STS GetCallerIdentity call, and returns the
+// identity they resolve to
+func ValidateAWSCredentials(profile string) (*CallerIdentity, error) {
+	return defaultCredentialResolver.Validate(profile)
+}
+
+// Validate resolves the credentials of a profile and calls STS
+// GetCallerIdentity with them
+func (r *AWSCredentialResolver) Validate(profile string) (*CallerIdentity, error) {
+	creds, err := r.Resolve(profile)
 	if err != nil {
-		return fmt.Errorf("failed to read credentials for profile '%s': %w", profile, err)
+		return nil, fmt.Errorf("failed to read credentials for profile '%s': %w", profile, err)
 	}
 
-	// Run aws sts get-caller-identity to validate credentials
-	cmd := exec.Command("aws", "sts", "get-caller-identity", "--profile", profile)
-
-	// Set environment with credentials
-	cmd.Env = append(os.Environ(), envVars...)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		outputStr := strings.TrimSpace(string(output))
-		if strings.Contains(outputStr, "ExpiredToken") || strings.Contains(outputStr, "expired") {
-			return fmt.Errorf("AWS credentials for profile '%s' have expired. Please refresh your credentials", profile)
-		}
-		if strings.Contains(outputStr, "InvalidClientTokenId") {
-			return fmt.Errorf("AWS credentials for profile '%s' are invalid", profile)
+	region := environmentRegion()
+	client := &stsClient{endpoint: r.STSEndpoint, region: region, httpClient: r.httpClient(), now: r.now}
+	if client.endpoint == "" {
+		client.endpoint = STSEndpoint(region)
+	}
+	identity, err := client.getCallerIdentity(creds)
+	var stsErr *STSError
+	if errors.As(err, &stsErr) {
+		switch stsErr.Code {
+		case "ExpiredToken", "RequestExpired":
+			return nil, fmt.Errorf("AWS credentials for profile '%s' have expired. Please refresh your credentials", profile)
+		case "InvalidClientTokenId", "SignatureDoesNotMatch", "UnrecognizedClientException":
+			return nil, fmt.Errorf("AWS credentials for profile '%s' are invalid", profile)
 		}
-		return fmt.Errorf("failed to validate AWS credentials for profile '%s': %s", profile, outputStr)
 	}
-
-	return nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate AWS credentials for profile '%s': %w", profile, err)
+	}
+	return identity, nil
 }
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// AWSTag is a tag in the format used by the aws CLI
+type AWSTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// RunAWS runs an aws CLI command and returns its JSON output
+func RunAWS(executor CommandExecutor, env []string, args ...string) (string, error) {
+	return RunCommandWithEnvOutput(executor, env, "aws", append(args, "--output", "json")...)
+}
+
+// AWSTagList converts a tag map into a list sorted by key
+func AWSTagList(tags map[string]string) []AWSTag {
+	list := make([]AWSTag, 0, len(tags))
+	for key, value := range tags {
+		list = append(list, AWSTag{Key: key, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// ParseAWSTags reads the tags from the output of the aws CLI, either a Tags
+// list (IAM) or a TagSet list (S3)
+func ParseAWSTags(output string) (map[string]string, error) {
+	var parsed struct {
+		Tags   []AWSTag `json:"Tags"`
+		TagSet []AWSTag `json:"TagSet"`
+	}
+	if output == "" {
+		return map[string]string{}, nil
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws CLI tags output: %w", err)
+	}
+	tags := map[string]string{}
+	for _, tag := range append(parsed.Tags, parsed.TagSet...) {
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// MissingTags returns the keys of want that are absent or different in got
+func MissingTags(want, got map[string]string) []string {
+	var missing []string
+	for key, value := range want {
+		if actual, ok := got[key]; !ok || actual != value {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ParseRolePermissionsBoundary returns the permissions boundary ARN from the
+// output of aws iam get-role, or an empty string when the role has none
+func ParseRolePermissionsBoundary(output string) (string, error) {
+	var parsed struct {
+		Role struct {
+			PermissionsBoundary struct {
+				PermissionsBoundaryArn string `json:"PermissionsBoundaryArn"`
+			} `json:"PermissionsBoundary"`
+		} `json:"Role"`
+	}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse aws iam get-role output: %w", err)
+	}
+	return parsed.Role.PermissionsBoundary.PermissionsBoundaryArn, nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAWSTags(t *testing.T) {
+	tags, err := ParseAWSTags(`{"Tags":[{"Key":"owner","Value":"team-a"}]}`)
+	if err != nil || tags["owner"] != "team-a" {
+		t.Errorf("Unexpected IAM tags %v (%v)", tags, err)
+	}
+	tags, err = ParseAWSTags(`{"TagSet":[{"Key":"expiry","Value":"2026-12-31"}]}`)
+	if err != nil || tags["expiry"] != "2026-12-31" {
+		t.Errorf("Unexpected S3 tags %v (%v)", tags, err)
+	}
+	if _, err := ParseAWSTags("not json"); err == nil {
+		t.Error("Expected error for invalid output")
+	}
+
+	missing := MissingTags(map[string]string{"owner": "team-a", "team": "x", "expiry": "1"}, map[string]string{"owner": "team-a", "team": "y"})
+	if !reflect.DeepEqual(missing, []string{"expiry", "team"}) {
+		t.Errorf("Expected missing [expiry team], got %v", missing)
+	}
+}
+
+func TestCcoctlRoleARNs(t *testing.T) {
+	dir := t.TempDir()
+	secret := "stringData:\n  credentials: |-\n    [default]\n    role_arn = arn:aws:iam::123456789012:role/path/test-cluster-ingress\n"
+	os.WriteFile(filepath.Join(dir, "openshift-ingress-operator-cloud-credentials-credentials.yaml"), []byte(secret), 0600)
+	os.WriteFile(filepath.Join(dir, "cluster-authentication-02-config.yaml"), []byte("kind: Authentication\n"), 0644)
+
+	arns, err := CcoctlRoleARNs(dir)
+	if err != nil {
+		t.Fatalf("CcoctlRoleARNs failed: %v", err)
+	}
+	if len(arns) != 1 || arns[0] != "arn:aws:iam::123456789012:role/path/test-cluster-ingress" {
+		t.Errorf("Unexpected role ARNs %v", arns)
+	}
+	if name := RoleNameFromARN(arns[0]); name != "test-cluster-ingress" {
+		t.Errorf("Expected role name test-cluster-ingress, got %s", name)
+	}
+}
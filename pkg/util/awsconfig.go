@@ -0,0 +1,169 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// awsSharedConfig holds the sections of the shared AWS credentials and
+// config files
+type awsSharedConfig struct {
+	// credentials maps a profile name to the keys of its section in the
+	// credentials file
+	credentials map[string]map[string]string
+	// profiles maps a profile name to the keys of its section in the config
+	// file, where profiles other than default are named "profile <name>"
+	profiles map[string]map[string]string
+	// ssoSessions maps an sso-session name to its keys in the config file
+	ssoSessions map[string]map[string]string
+}
+
+// sharedCredentialsFile returns the path of the shared credentials file,
+// overridden by AWS_SHARED_CREDENTIALS_FILE
+func sharedCredentialsFile() (string, error) {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "credentials"), nil
+}
+
+// sharedConfigFile returns the path of the shared config file, overridden by
+// AWS_CONFIG_FILE
+func sharedConfigFile() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// loadAWSSharedConfig reads both shared files. A missing file is empty.
+func loadAWSSharedConfig() (*awsSharedConfig, error) {
+	config := &awsSharedConfig{
+		credentials: map[string]map[string]string{},
+		profiles:    map[string]map[string]string{},
+		ssoSessions: map[string]map[string]string{},
+	}
+
+	credentialsPath, err := sharedCredentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	sections, err := readINIFile(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	config.credentials = sections
+
+	configPath, err := sharedConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	sections, err = readINIFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for name, keys := range sections {
+		switch {
+		case name == "default":
+			config.profiles[name] = keys
+		case strings.HasPrefix(name, "profile "):
+			config.profiles[strings.TrimSpace(strings.TrimPrefix(name, "profile "))] = keys
+		case strings.HasPrefix(name, "sso-session "):
+			config.ssoSessions[strings.TrimSpace(strings.TrimPrefix(name, "sso-session "))] = keys
+		}
+	}
+	return config, nil
+}
+
+// profile returns the keys of a profile, those of the credentials file
+// taking precedence over those of the config file
+func (c *awsSharedConfig) profile(name string) (map[string]string, bool) {
+	fromConfig, inConfig := c.profiles[name]
+	fromCredentials, inCredentials := c.credentials[name]
+	if !inConfig && !inCredentials {
+		return nil, false
+	}
+	keys := map[string]string{}
+	for key, value := range fromConfig {
+		keys[key] = value
+	}
+	for key, value := range fromCredentials {
+		keys[key] = value
+	}
+	return keys, true
+}
+
+// AWSProfileRoleARN returns the role_arn of a profile of the shared files
+func AWSProfileRoleARN(profile string) (string, error) {
+	config, err := loadAWSSharedConfig()
+	if err != nil {
+		return "", err
+	}
+	keys, ok := config.profile(profile)
+	if !ok {
+		return "", fmt.Errorf("profile '%s' not found", profile)
+	}
+	if keys["role_arn"] == "" {
+		return "", fmt.Errorf("profile '%s' has no role_arn", profile)
+	}
+	return keys["role_arn"], nil
+}
+
+// readINIFile parses an AWS shared file into its sections. Nested values
+// (indented lines, as used by s3 settings) are ignored.
+func readINIFile(path string) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var section map[string]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			if sections[name] == nil {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+			continue
+		}
+
+		if section == nil || strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return sections, nil
+}
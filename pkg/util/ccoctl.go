@@ -0,0 +1,50 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CcoctlStateFile records the progress of the Step 7 sub-steps in the output directory
+const CcoctlStateFile = "ccoctl-state.json"
+
+var roleARNLine = regexp.MustCompile(`role_arn\s*=\s*(arn:aws[a-z-]*:iam::\d{12}:role/\S+)`)
+
+// CcoctlRoleARNs returns the IAM role ARNs referenced by the credentials
+// secrets that ccoctl writes to its manifests directory
+func CcoctlRoleARNs(manifestsDir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(manifestsDir, "*-credentials.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var arns []string
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range roleARNLine.FindAllStringSubmatch(string(content), -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				arns = append(arns, match[1])
+			}
+		}
+	}
+	sort.Strings(arns)
+	return arns, nil
+}
+
+// RoleNameFromARN returns the role name of an IAM role ARN, without its path
+func RoleNameFromARN(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// OIDCBucketName returns the name of the S3 bucket ccoctl creates for the OIDC discovery documents
+func OIDCBucketName(clusterName string) string {
+	return clusterName + "-oidc"
+}
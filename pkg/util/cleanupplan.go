@@ -0,0 +1,71 @@
+package util
+
+import "path/filepath"
+
+// CleanupPlan lists the resources cleanup would remove for an installation
+type CleanupPlan struct {
+	// DestroyDir is the openshift-install destroy target, empty without infrastructure
+	DestroyDir             string
+	InfraID                string
+	IAMRoles               []string
+	OIDCProvider           string
+	S3Bucket               string
+	CloudFrontDistribution string
+}
+
+// Empty reports whether no resource was found
+func (p *CleanupPlan) Empty() bool {
+	return p.DestroyDir == "" && len(p.IAMRoles) == 0 && p.OIDCProvider == "" && p.S3Bucket == "" && p.CloudFrontDistribution == ""
+}
+
+// CleanupPlan builds the cleanup plan of the installation from the
+// workspace and the inventory. ccoctl resources are only listed when Step 7
+// left a trace; withIAM is false when the IAM resources are not ours (byoIAM).
+func (i *Installation) CleanupPlan(inventory *Inventory, withIAM bool) (*CleanupPlan, error) {
+	plan := &CleanupPlan{InfraID: i.InfraID}
+	if i.VersionArch != "" && i.HasInfrastructure() {
+		plan.DestroyDir = i.VersionDir()
+	}
+	if !withIAM {
+		return plan, nil
+	}
+
+	if inventory != nil && inventory.RecordsInstallation(i) {
+		for _, arn := range inventory.IAMRoles {
+			plan.IAMRoles = append(plan.IAMRoles, RoleNameFromARN(arn))
+		}
+		plan.OIDCProvider = inventory.OIDCProviderARN
+		plan.S3Bucket = inventory.S3Bucket
+		plan.CloudFrontDistribution = inventory.CloudFrontDistribution
+		if plan.InfraID == "" {
+			plan.InfraID = inventory.InfraID
+		}
+	}
+
+	outputDir := filepath.Join(i.VersionDir(), "_output")
+	ccoctlRan := DirExistsWithFiles(filepath.Join(outputDir, "manifests")) || FileExists(filepath.Join(outputDir, CcoctlStateFile))
+	if i.VersionArch == "" || !ccoctlRan {
+		return plan, nil
+	}
+
+	if len(plan.IAMRoles) == 0 {
+		requests, err := ReadCredentialsRequests(filepath.Join(i.VersionDir(), "credreqs"))
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			plan.IAMRoles = append(plan.IAMRoles, CcoctlRoleName(i.ClusterName, request))
+		}
+	}
+	if plan.S3Bucket == "" {
+		plan.S3Bucket = OIDCBucketName(i.ClusterName)
+	}
+	issuer := i.IssuerHost()
+	if plan.OIDCProvider == "" {
+		plan.OIDCProvider = issuer
+	}
+	if plan.CloudFrontDistribution == "" && i.PrivateBucket {
+		plan.CloudFrontDistribution = "distribution serving " + issuer
+	}
+	return plan, nil
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCleanupPlan(t *testing.T) {
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, "artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(dir, "credreqs"), 0755)
+	os.MkdirAll(filepath.Join(dir, "_output", "manifests"), 0755)
+	os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(`{"clusterName":"dev","infraID":"dev-x7k2p"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "_output", "manifests", "openshift-image-registry-installer-cloud-credentials-credentials.yaml"), []byte("kind: Secret\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "credreqs", "0000_50_cluster-image-registry-operator_01-registry-credentials-request.yaml"), []byte(`apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-image-registry
+spec:
+  secretRef:
+    name: installer-cloud-credentials
+    namespace: openshift-image-registry
+`), 0644)
+
+	installation := &Installation{Workspace: workspace, VersionArch: "4.12.0-x86_64", ClusterName: "dev", Region: "us-east-2", InfraID: "dev-x7k2p"}
+
+	t.Run("from workspace", func(t *testing.T) {
+		plan, err := installation.CleanupPlan(&Inventory{}, true)
+		if err != nil {
+			t.Fatalf("CleanupPlan failed: %v", err)
+		}
+		want := &CleanupPlan{
+			DestroyDir:   dir,
+			InfraID:      "dev-x7k2p",
+			IAMRoles:     []string{"dev-openshift-image-registry-installer-cloud-credentials"},
+			OIDCProvider: "dev-oidc.s3.us-east-2.amazonaws.com",
+			S3Bucket:     "dev-oidc",
+		}
+		if !reflect.DeepEqual(plan, want) {
+			t.Errorf("Expected %+v, got %+v", want, plan)
+		}
+	})
+
+	t.Run("inventory takes precedence", func(t *testing.T) {
+		inventory := &Inventory{
+			ClusterName:            "dev",
+			IAMRoles:               []string{"arn:aws:iam::123456789012:role/dev-openshift-ingress-operator-cloud-credentials"},
+			OIDCProviderARN:        "arn:aws:iam::123456789012:oidc-provider/d1234abcd.cloudfront.net",
+			S3Bucket:               "dev-oidc",
+			CloudFrontDistribution: "E2QWRUHAPOMQZL",
+		}
+		plan, err := installation.CleanupPlan(inventory, true)
+		if err != nil {
+			t.Fatalf("CleanupPlan failed: %v", err)
+		}
+		if !reflect.DeepEqual(plan.IAMRoles, []string{"dev-openshift-ingress-operator-cloud-credentials"}) {
+			t.Errorf("Expected the inventory roles, got %v", plan.IAMRoles)
+		}
+		if plan.OIDCProvider != inventory.OIDCProviderARN || plan.CloudFrontDistribution != "E2QWRUHAPOMQZL" {
+			t.Errorf("Expected the inventory OIDC resources, got %+v", plan)
+		}
+	})
+
+	t.Run("inventory of another release", func(t *testing.T) {
+		inventory := &Inventory{
+			ClusterName:  "dev",
+			ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.11.0-x86_64",
+			IAMRoles:     []string{"arn:aws:iam::123456789012:role/dev-openshift-ingress-operator-cloud-credentials"},
+			InfraID:      "dev-a1b2c",
+		}
+		other := *installation
+		other.InfraID = ""
+		plan, err := other.CleanupPlan(inventory, true)
+		if err != nil {
+			t.Fatalf("CleanupPlan failed: %v", err)
+		}
+		if plan.InfraID != "" || !reflect.DeepEqual(plan.IAMRoles, []string{"dev-openshift-image-registry-installer-cloud-credentials"}) {
+			t.Errorf("Expected the inventory to be ignored, got %+v", plan)
+		}
+	})
+
+	t.Run("byoIAM", func(t *testing.T) {
+		plan, err := installation.CleanupPlan(&Inventory{}, false)
+		if err != nil {
+			t.Fatalf("CleanupPlan failed: %v", err)
+		}
+		if plan.DestroyDir != dir || len(plan.IAMRoles) != 0 || plan.S3Bucket != "" {
+			t.Errorf("Expected only the destroy target, got %+v", plan)
+		}
+	})
+
+	t.Run("nothing installed", func(t *testing.T) {
+		empty := &Installation{Workspace: t.TempDir(), VersionArch: "4.13.0-x86_64", ClusterName: "test", Region: "eu-west-1"}
+		plan, err := empty.CleanupPlan(&Inventory{}, true)
+		if err != nil {
+			t.Fatalf("CleanupPlan failed: %v", err)
+		}
+		if !plan.Empty() {
+			t.Errorf("Expected an empty plan, got %+v", plan)
+		}
+	})
+}
+
+func TestRemoveInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), InventoryFile)
+	inventory := &Inventory{ClusterName: "dev", ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.12.0-x86_64", InfraID: "dev-x7k2p"}
+	if err := inventory.Save(path); err != nil {
+		t.Fatalf("Failed to save inventory: %v", err)
+	}
+
+	if err := RemoveInventory(path, &Installation{ClusterName: "dev", VersionArch: "4.13.0-x86_64"}); err != nil {
+		t.Fatalf("RemoveInventory failed: %v", err)
+	}
+	if !FileExists(path) {
+		t.Fatal("Expected the inventory of another release to be kept")
+	}
+
+	if err := RemoveInventory(path, &Installation{ClusterName: "dev", VersionArch: "4.12.0-x86_64"}); err != nil {
+		t.Fatalf("RemoveInventory failed: %v", err)
+	}
+	if FileExists(path) {
+		t.Error("Expected the inventory to be removed")
+	}
+}
@@ -0,0 +1,389 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credential sources
+const (
+	CredentialSourceStatic      = "static keys"
+	CredentialSourceEnvironment = "environment"
+	CredentialSourceAssumeRole  = "assume role"
+	CredentialSourceProcess     = "credential_process"
+	CredentialSourceSSO         = "sso"
+)
+
+// credentialRefreshWindow is how long before they expire cached temporary
+// credentials are resolved again
+const credentialRefreshWindow = 5 * time.Minute
+
+// AWSCredentialResolver resolves a profile of the shared AWS files into
+// credentials: role_arn with source_profile or credential_source (prompting
+// for the mfa_serial code), SSO, static keys and credential_process. The
+// default profile falls back to the AWS_* environment variables. Temporary
+// credentials are cached until they are about to expire.
+type AWSCredentialResolver struct {
+	// STSEndpoint and SSOEndpoint override the endpoints derived from the
+	// region and the AWS_ENDPOINT_URL_* variables
+	STSEndpoint string
+	SSOEndpoint string
+	// PromptMFA reads the code of an MFA device, from stdin by default
+	PromptMFA  func(serial string) (string, error)
+	HTTPClient *http.Client
+	Now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*AWSCredentials
+}
+
+var defaultCredentialResolver = &AWSCredentialResolver{}
+
+// ResolveAWSCredentials resolves the credentials of a profile with the
+// default resolver
+func ResolveAWSCredentials(profile string) (*AWSCredentials, error) {
+	return defaultCredentialResolver.Resolve(profile)
+}
+
+// Resolve returns the credentials of a profile, "default" when empty
+func (r *AWSCredentialResolver) Resolve(profile string) (*AWSCredentials, error) {
+	if profile == "" {
+		profile = "default"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if creds, ok := r.cache[profile]; ok && r.now().Add(credentialRefreshWindow).Before(creds.Expiration) {
+		return creds, nil
+	}
+
+	config, err := loadAWSSharedConfig()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := r.resolve(config, profile, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	// Long-term keys are read again every time, they may be edited meanwhile
+	if !creds.Expiration.IsZero() {
+		if r.cache == nil {
+			r.cache = map[string]*AWSCredentials{}
+		}
+		r.cache[profile] = creds
+	}
+	return creds, nil
+}
+
+// Refresh resolves the credentials of a profile again, ignoring the cache.
+// Assumed roles, SSO and credential_process then return new credentials.
+func (r *AWSCredentialResolver) Refresh(profile string) (*AWSCredentials, error) {
+	if profile == "" {
+		profile = "default"
+	}
+	r.mu.Lock()
+	delete(r.cache, profile)
+	r.mu.Unlock()
+	return r.Resolve(profile)
+}
+
+// RefreshAWSCredentials resolves the credentials of a profile again with the
+// default resolver
+func RefreshAWSCredentials(profile string) (*AWSCredentials, error) {
+	return defaultCredentialResolver.Refresh(profile)
+}
+
+func (r *AWSCredentialResolver) resolve(config *awsSharedConfig, name string, visited map[string]bool) (*AWSCredentials, error) {
+	if visited[name] {
+		return nil, fmt.Errorf("profile '%s' is part of a source_profile loop", name)
+	}
+	visited[name] = true
+
+	keys, ok := config.profile(name)
+	switch {
+	case !ok:
+	case keys["role_arn"] != "":
+		return r.assumeRoleProfile(config, name, keys, visited)
+	case keys["sso_session"] != "" || keys["sso_start_url"] != "":
+		return r.ssoProfile(config, name, keys)
+	case staticCredentials(keys) != nil:
+		return staticCredentials(keys), nil
+	case keys["credential_process"] != "":
+		return runCredentialProcess(keys["credential_process"])
+	}
+
+	// As with the AWS CLI, the environment provides the default credentials
+	if name == "default" {
+		if creds := environmentCredentials(); creds != nil {
+			return creds, nil
+		}
+	}
+	return nil, fmt.Errorf("profile '%s' not found or missing required credentials", name)
+}
+
+func (r *AWSCredentialResolver) assumeRoleProfile(config *awsSharedConfig, name string, keys map[string]string, visited map[string]bool) (*AWSCredentials, error) {
+	var source *AWSCredentials
+	switch {
+	case keys["source_profile"] == name:
+		// A profile may hold the keys used to assume its own role
+		source = staticCredentials(keys)
+		if source == nil {
+			return nil, fmt.Errorf("profile '%s' is its own source_profile but has no static keys", name)
+		}
+	case keys["source_profile"] != "":
+		var err error
+		if source, err = r.resolve(config, keys["source_profile"], visited); err != nil {
+			return nil, fmt.Errorf("failed to resolve source_profile of profile '%s': %w", name, err)
+		}
+	case keys["credential_source"] == "Environment":
+		if source = environmentCredentials(); source == nil {
+			return nil, fmt.Errorf("profile '%s' uses credential_source Environment but AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set", name)
+		}
+	case keys["credential_source"] != "":
+		return nil, fmt.Errorf("credential_source %s of profile '%s' is not supported, use source_profile", keys["credential_source"], name)
+	default:
+		return nil, fmt.Errorf("profile '%s' has a role_arn but no source_profile or credential_source", name)
+	}
+
+	input := assumeRoleInput{
+		RoleARN:     keys["role_arn"],
+		SessionName: keys["role_session_name"],
+		ExternalID:  keys["external_id"],
+		MFASerial:   keys["mfa_serial"],
+	}
+	if input.SessionName == "" {
+		input.SessionName = fmt.Sprintf("openshift-sts-installer-%d", r.now().Unix())
+	}
+	if duration := keys["duration_seconds"]; duration != "" {
+		seconds, err := strconv.Atoi(duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration_seconds %q in profile '%s'", duration, name)
+		}
+		input.DurationSeconds = seconds
+	}
+	if input.MFASerial != "" {
+		code, err := r.promptMFA(input.MFASerial)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the MFA code for profile '%s': %w", name, err)
+		}
+		input.MFACode = code
+	}
+
+	region := keys["region"]
+	if region == "" {
+		region = environmentRegion()
+	}
+	client := &stsClient{endpoint: r.STSEndpoint, region: region, httpClient: r.httpClient(), now: r.now}
+	if client.endpoint == "" {
+		client.endpoint = STSEndpoint(region)
+	}
+	return client.assumeRole(source, input)
+}
+
+func (r *AWSCredentialResolver) ssoProfile(config *awsSharedConfig, name string, keys map[string]string) (*AWSCredentials, error) {
+	region := keys["sso_region"]
+	// The legacy format caches the token under the start URL
+	cacheKey := keys["sso_start_url"]
+	if session := keys["sso_session"]; session != "" {
+		sessionKeys, ok := config.ssoSessions[session]
+		if !ok {
+			return nil, fmt.Errorf("sso-session '%s' of profile '%s' not found", session, name)
+		}
+		region = sessionKeys["sso_region"]
+		cacheKey = session
+	}
+	if keys["sso_account_id"] == "" || keys["sso_role_name"] == "" || region == "" {
+		return nil, fmt.Errorf("profile '%s' needs sso_account_id, sso_role_name and sso_region", name)
+	}
+
+	token, err := readSSOToken(cacheKey, r.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w, run: aws sso login --profile %s", err, name)
+	}
+	endpoint := r.SSOEndpoint
+	if endpoint == "" {
+		endpoint = SSOEndpoint(region)
+	}
+	return ssoRoleCredentials(r.httpClient(), endpoint, token, keys["sso_account_id"], keys["sso_role_name"])
+}
+
+func (r *AWSCredentialResolver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *AWSCredentialResolver) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+func (r *AWSCredentialResolver) promptMFA(serial string) (string, error) {
+	if r.PromptMFA != nil {
+		return r.PromptMFA(serial)
+	}
+	fmt.Fprintf(os.Stderr, "Enter MFA code for %s: ", serial)
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(code), nil
+}
+
+// environmentCredentials returns the credentials set in the AWS_*
+// environment variables, nil when there are none
+func environmentCredentials() *AWSCredentials {
+	creds := &AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Source:          CredentialSourceEnvironment,
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil
+	}
+	if expiration, err := time.Parse(time.RFC3339, os.Getenv("AWS_CREDENTIAL_EXPIRATION")); err == nil {
+		creds.Expiration = expiration
+	}
+	return creds
+}
+
+func environmentRegion() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// readSSOToken reads the access token `aws sso login` cached for a session
+// name or a start URL
+func readSSOToken(cacheKey string, now time.Time) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sum := sha1.Sum([]byte(cacheKey))
+	path := filepath.Join(homeDir, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("no cached SSO token for %s", cacheKey)
+	}
+
+	var token struct {
+		AccessToken string `json:"accessToken"`
+		ExpiresAt   string `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(content, &token); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	if err != nil {
+		// Older CLI versions write the zone as UTC
+		expiresAt, err = time.Parse("2006-01-02T15:04:05UTC", token.ExpiresAt)
+	}
+	if err != nil || token.AccessToken == "" || !now.Before(expiresAt) {
+		return "", fmt.Errorf("the cached SSO token for %s has expired", cacheKey)
+	}
+	return token.AccessToken, nil
+}
+
+// runCredentialProcess runs the credential_process command of a profile and
+// reads the credentials it prints
+func runCredentialProcess(command string) (*AWSCredentials, error) {
+	args := splitCommandLine(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty credential_process")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential_process %s failed: %w", args[0], err)
+	}
+
+	var parsed struct {
+		Version         int    `json:"Version"`
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+		Expiration      string `json:"Expiration"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse credential_process output: %w", err)
+	}
+	if parsed.Version != 1 {
+		return nil, fmt.Errorf("unsupported credential_process output version %d", parsed.Version)
+	}
+	if parsed.AccessKeyID == "" || parsed.SecretAccessKey == "" {
+		return nil, fmt.Errorf("credential_process returned no AccessKeyId or SecretAccessKey")
+	}
+
+	creds := &AWSCredentials{
+		AccessKeyID:     parsed.AccessKeyID,
+		SecretAccessKey: parsed.SecretAccessKey,
+		SessionToken:    parsed.SessionToken,
+		Source:          CredentialSourceProcess,
+	}
+	if parsed.Expiration != "" {
+		if creds.Expiration, err = time.Parse(time.RFC3339, parsed.Expiration); err != nil {
+			return nil, fmt.Errorf("invalid Expiration in credential_process output: %w", err)
+		}
+	}
+	return creds, nil
+}
+
+// splitCommandLine splits a command line on spaces, honoring single and
+// double quotes and backslash escapes. No shell is involved.
+func splitCommandLine(command string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, c := range command {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
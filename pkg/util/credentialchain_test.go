@@ -0,0 +1,325 @@
+package util
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// awsTestHome points HOME at an empty directory and clears the AWS
+// variables inherited from the shell
+func awsTestHome(t *testing.T) string {
+	home := t.TempDir()
+	os.MkdirAll(filepath.Join(home, ".aws"), 0755)
+	t.Setenv("HOME", home)
+	for _, name := range append(conflictingAWSEnvVars, "AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE",
+		"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_STS", "AWS_ENDPOINT_URL_SSO") {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	return home
+}
+
+// fakeSTS answers AssumeRole with credentials named after the role session
+func fakeSTS(t *testing.T, calls *[]url.Values) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		params, _ := url.ParseQuery(string(body))
+		*calls = append(*calls, params)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>MissingAuthenticationToken</Code><Message>unsigned</Message></Error></ErrorResponse>`)
+			return
+		}
+		if params.Get("RoleArn") == "arn:aws:iam::123456789012:role/denied" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>AccessDenied</Code><Message>not authorized</Message></Error></ErrorResponse>`)
+			return
+		}
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+			<AccessKeyId>ASIA%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>
+			<SessionToken>token</SessionToken><Expiration>2030-01-01T00:00:00Z</Expiration>
+		</Credentials></AssumeRoleResult></AssumeRoleResponse>`, strings.ToUpper(params.Get("RoleSessionName")))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveAssumeRole(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = base-secret
+`), 0600)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`[profile installer]
+role_arn = arn:aws:iam::123456789012:role/installer
+source_profile = base
+role_session_name = installer
+mfa_serial = arn:aws:iam::123456789012:mfa/admin
+region = us-east-2
+
+[profile chained]
+role_arn = arn:aws:iam::210987654321:role/chained
+source_profile = installer
+role_session_name = chained
+
+[profile denied]
+role_arn = arn:aws:iam::123456789012:role/denied
+source_profile = base
+
+[profile loop-a]
+role_arn = arn:aws:iam::123456789012:role/a
+source_profile = loop-b
+
+[profile loop-b]
+role_arn = arn:aws:iam::123456789012:role/b
+source_profile = loop-a
+`), 0600)
+
+	var calls []url.Values
+	server := fakeSTS(t, &calls)
+	prompts := 0
+	resolver := &AWSCredentialResolver{
+		STSEndpoint: server.URL,
+		PromptMFA: func(serial string) (string, error) {
+			prompts++
+			return "123456", nil
+		},
+		Now: func() time.Time { return time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	creds, err := resolver.Resolve("installer")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAINSTALLER" || creds.SessionToken != "token" || creds.Source != CredentialSourceAssumeRole {
+		t.Errorf("Unexpected credentials %+v", creds)
+	}
+	if !creds.Expiration.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected expiration %v", creds.Expiration)
+	}
+	call := calls[0]
+	if call.Get("Action") != "AssumeRole" || call.Get("RoleArn") != "arn:aws:iam::123456789012:role/installer" ||
+		call.Get("SerialNumber") != "arn:aws:iam::123456789012:mfa/admin" || call.Get("TokenCode") != "123456" {
+		t.Errorf("Unexpected AssumeRole call %v", call)
+	}
+
+	// Cached until close to expiry: no second call, no second MFA prompt
+	if _, err := resolver.Resolve("installer"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(calls) != 1 || prompts != 1 {
+		t.Errorf("Expected the credentials to be cached, got %d calls and %d prompts", len(calls), prompts)
+	}
+
+	creds, err = resolver.Resolve("chained")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if creds.AccessKeyID != "ASIACHAINED" || len(calls) != 3 {
+		t.Errorf("Expected the chained role assumed from the installer role, got %+v after %d calls", creds, len(calls))
+	}
+
+	if _, err := resolver.Resolve("denied"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected AccessDenied, got %v", err)
+	}
+	if _, err := resolver.Resolve("loop-a"); err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Errorf("Expected a source_profile loop error, got %v", err)
+	}
+}
+
+func TestResolveSharedFiles(t *testing.T) {
+	home := awsTestHome(t)
+	credentials := filepath.Join(home, "custom-credentials")
+	config := filepath.Join(home, "custom-config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	t.Setenv("AWS_CONFIG_FILE", config)
+	os.WriteFile(credentials, []byte(`[both]
+aws_access_key_id = AKIAFROMCREDENTIALS
+aws_secret_access_key = credentials-secret
+`), 0600)
+	os.WriteFile(config, []byte(`[profile config-only]
+aws_access_key_id = AKIAFROMCONFIG
+aws_secret_access_key = config-secret
+
+[profile both]
+aws_access_key_id = AKIAIGNORED
+aws_secret_access_key = ignored
+region = eu-west-1
+`), 0600)
+
+	resolver := &AWSCredentialResolver{}
+	for profile, want := range map[string]string{"config-only": "AKIAFROMCONFIG", "both": "AKIAFROMCREDENTIALS"} {
+		creds, err := resolver.Resolve(profile)
+		if err != nil {
+			t.Fatalf("Resolve %s failed: %v", profile, err)
+		}
+		if creds.AccessKeyID != want || creds.Source != CredentialSourceStatic {
+			t.Errorf("Expected %s for profile %s, got %+v", want, profile, creds)
+		}
+	}
+
+	// Without a default profile, the environment provides the credentials
+	if _, err := resolver.Resolve("default"); err == nil {
+		t.Error("Expected an error without a default profile or environment credentials")
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err := resolver.Resolve("")
+	if err != nil || creds.AccessKeyID != "AKIAENV" || creds.Source != CredentialSourceEnvironment {
+		t.Errorf("Expected the environment credentials, got %+v, %v", creds, err)
+	}
+	// But they never replace a named profile
+	if _, err := resolver.Resolve("missing"); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
+
+func TestResolveCredentialProcess(t *testing.T) {
+	home := awsTestHome(t)
+	script := filepath.Join(home, "print credentials.sh")
+	os.WriteFile(script, []byte(`#!/bin/sh
+echo '{"Version": 1, "AccessKeyId": "ASIAPROCESS", "SecretAccessKey": "process-secret", "SessionToken": "'"$1"'", "Expiration": "2030-01-01T00:00:00Z"}'
+`), 0755)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(fmt.Sprintf(`[profile process]
+credential_process = "%s" process-token
+`, script)), 0600)
+
+	creds, err := (&AWSCredentialResolver{}).Resolve("process")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAPROCESS" || creds.SessionToken != "process-token" || creds.Expiration.Year() != 2030 {
+		t.Errorf("Unexpected credentials %+v", creds)
+	}
+}
+
+func TestResolveSSO(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`[profile sso]
+sso_session = corp
+sso_account_id = 123456789012
+sso_role_name = Installer
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = us-east-1
+`), 0600)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-amz-sso_bearer_token") != "sso-token" || r.URL.Query().Get("account_id") != "123456789012" ||
+			r.URL.Query().Get("role_name") != "Installer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"roleCredentials": {"accessKeyId": "ASIASSO", "secretAccessKey": "sso-secret", "sessionToken": "token", "expiration": 1893456000000}}`)
+	}))
+	defer server.Close()
+	resolver := &AWSCredentialResolver{SSOEndpoint: server.URL}
+
+	if _, err := resolver.Resolve("sso"); err == nil || !strings.Contains(err.Error(), "aws sso login --profile sso") {
+		t.Errorf("Expected a login hint without a cached token, got %v", err)
+	}
+
+	sum := sha1.Sum([]byte("corp"))
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	os.MkdirAll(cacheDir, 0700)
+	os.WriteFile(filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json"),
+		[]byte(`{"accessToken": "sso-token", "expiresAt": "2099-01-01T00:00:00Z"}`), 0600)
+
+	creds, err := resolver.Resolve("sso")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if creds.AccessKeyID != "ASIASSO" || creds.Source != CredentialSourceSSO || !creds.Expiration.Equal(time.Unix(1893456000, 0)) {
+		t.Errorf("Unexpected credentials %+v", creds)
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	got := splitCommandLine(`/usr/bin/vault-creds --role 'ocp installer' "a \"quoted\" arg" plain\ space`)
+	want := []string{"/usr/bin/vault-creds", "--role", "ocp installer", `a "quoted" arg`, "plain space"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[saml]
+aws_access_key_id = ASIASAML
+aws_secret_access_key = secret
+aws_session_token = token
+x_security_token_expires = 2030-01-01T01:00:00+01:00
+
+[session]
+aws_access_key_id = ASIASESSION
+aws_secret_access_key = secret
+aws_session_token = token
+
+[static]
+aws_access_key_id = AKIASTATIC
+aws_secret_access_key = secret
+`), 0600)
+	now := time.Date(2029, 12, 31, 23, 30, 0, 0, time.UTC)
+	resolver := &AWSCredentialResolver{Now: func() time.Time { return now }}
+
+	saml, err := resolver.Resolve("saml")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !saml.Expiration.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected expiration %v", saml.Expiration)
+	}
+	if !saml.ExpiresWithin(time.Hour, now) || saml.ExpiresWithin(10*time.Minute, now) || saml.ExpiryUnknown() {
+		t.Errorf("Expected the saml credentials to expire in 30 minutes, got %v", saml.Expiration)
+	}
+
+	session, _ := resolver.Resolve("session")
+	if !session.ExpiryUnknown() || session.ExpiresWithin(time.Hour, now) {
+		t.Errorf("Expected a session of unknown expiry, got %+v", session)
+	}
+	static, _ := resolver.Resolve("static")
+	if static.ExpiryUnknown() || static.ExpiresWithin(time.Hour, now) {
+		t.Errorf("Expected long-term keys never to expire, got %+v", static)
+	}
+}
+
+func TestRefreshIgnoresCache(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = base-secret
+`), 0600)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`[profile installer]
+role_arn = arn:aws:iam::123456789012:role/installer
+source_profile = base
+`), 0600)
+
+	var calls []url.Values
+	server := fakeSTS(t, &calls)
+	resolver := &AWSCredentialResolver{
+		STSEndpoint: server.URL,
+		Now:         func() time.Time { return time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+	resolver.Resolve("installer")
+	resolver.Resolve("installer")
+	if len(calls) != 1 {
+		t.Fatalf("Expected the credentials to be cached, got %d calls", len(calls))
+	}
+	if _, err := resolver.Refresh("installer"); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("Expected Refresh to assume the role again, got %d calls", len(calls))
+	}
+}
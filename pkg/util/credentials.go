@@ -0,0 +1,85 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conflictingAWSEnvVars are the inherited variables the AWS SDKs and CLI
+// read credentials from before the shared credentials file. They are cleared
+// so that the selected profile is always the one used.
+var conflictingAWSEnvVars = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_SECURITY_TOKEN",
+	"AWS_CREDENTIAL_EXPIRATION",
+	"AWS_PROFILE",
+	"AWS_DEFAULT_PROFILE",
+	"AWS_ROLE_ARN",
+	"AWS_ROLE_SESSION_NAME",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+	"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN",
+}
+
+// AWSCredentialProvider provides the AWS credentials of the configured
+// profile to every command that calls AWS
+type AWSCredentialProvider struct {
+	Profile string
+}
+
+// NewAWSCredentialProvider creates a provider for the given profile
+func NewAWSCredentialProvider(profile string) *AWSCredentialProvider {
+	return &AWSCredentialProvider{Profile: profile}
+}
+
+// Env returns the environment entries that make a command use the profile
+// credentials: conflicting inherited variables are unset (see UnsetEnv),
+// then the credentials are set
+func (p *AWSCredentialProvider) Env() ([]string, error) {
+	credentials, err := GetAWSEnvVars(p.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS credentials from profile '%s': %w", p.Profile, err)
+	}
+	env := make([]string, 0, len(conflictingAWSEnvVars)+len(credentials))
+	for _, name := range conflictingAWSEnvVars {
+		env = append(env, UnsetEnv(name))
+	}
+	return append(env, credentials...), nil
+}
+
+// UnsetEnv returns the entry that removes name from the environment of a
+// command when passed to a CommandExecutor
+func UnsetEnv(name string) string {
+	return name
+}
+
+// MergeEnv applies overrides to base. Entries in KEY=value form replace any
+// previous value of KEY; entries without "=" remove KEY.
+func MergeEnv(base, overrides []string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	values := map[string]string{}
+	for _, entry := range append(append([]string{}, base...), overrides...) {
+		key, _, set := strings.Cut(entry, "=")
+		if !set {
+			delete(values, key)
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+		values[key] = entry
+	}
+
+	merged := make([]string, 0, len(values))
+	for _, key := range keys {
+		if entry, ok := values[key]; ok {
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAWSCredentialProviderEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, ".aws"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, ".aws", "credentials"), []byte(`[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = default-secret
+
+[installer]
+aws_access_key_id = AKIAINSTALLER
+aws_secret_access_key = installer-secret
+`), 0600)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	env, err := NewAWSCredentialProvider("installer").Env()
+	if err != nil {
+		t.Fatalf("Env failed: %v", err)
+	}
+
+	// Credentials inherited from the shell must not win over the profile
+	inherited := []string{
+		"PATH=/usr/bin",
+		"AWS_ACCESS_KEY_ID=AKIASHELL",
+		"AWS_SECRET_ACCESS_KEY=shell-secret",
+		"AWS_SESSION_TOKEN=shell-token",
+		"AWS_PROFILE=default",
+	}
+	want := []string{
+		"PATH=/usr/bin",
+		"AWS_ACCESS_KEY_ID=AKIAINSTALLER",
+		"AWS_SECRET_ACCESS_KEY=installer-secret",
+	}
+	if got := MergeEnv(inherited, env); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := NewAWSCredentialProvider("missing").Env(); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      []string
+		overrides []string
+		want      []string
+	}{
+		{
+			name:      "override keeps position",
+			base:      []string{"A=1", "B=2"},
+			overrides: []string{"A=3"},
+			want:      []string{"A=3", "B=2"},
+		},
+		{
+			name:      "unset",
+			base:      []string{"A=1", "B=2"},
+			overrides: []string{UnsetEnv("A")},
+			want:      []string{"B=2"},
+		},
+		{
+			name:      "unset then set",
+			base:      []string{"A=1", "B=2"},
+			overrides: []string{UnsetEnv("A"), "A=3"},
+			want:      []string{"A=3", "B=2"},
+		},
+		{
+			name:      "empty value is kept",
+			base:      []string{"A=1"},
+			overrides: []string{"NO_PROXY="},
+			want:      []string{"A=1", "NO_PROXY="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeEnv(tt.base, tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
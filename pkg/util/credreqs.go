@@ -0,0 +1,76 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialsRequest represents the fields we need from a CredentialsRequest manifest
+type CredentialsRequest struct {
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		SecretRef struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"secretRef"`
+		ServiceAccountNames []string `yaml:"serviceAccountNames"`
+	} `yaml:"spec"`
+	// File is the manifest the request was read from
+	File string `yaml:"-"`
+}
+
+// ReadCredentialsRequests reads every CredentialsRequest in the credreqs directory
+func ReadCredentialsRequests(dir string) ([]CredentialsRequest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var requests []CredentialsRequest
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		for {
+			var doc struct {
+				Kind               string `yaml:"kind"`
+				CredentialsRequest `yaml:",inline"`
+			}
+			err := decoder.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			if doc.Kind != "CredentialsRequest" {
+				continue
+			}
+			doc.File = path
+			requests = append(requests, doc.CredentialsRequest)
+		}
+	}
+	return requests, nil
+}
+
+// CcoctlRoleName returns the name ccoctl gives to the IAM role of a
+// CredentialsRequest: <name>-<secret namespace>-<secret name>, at most 64 characters
+func CcoctlRoleName(name string, request CredentialsRequest) string {
+	roleName := fmt.Sprintf("%s-%s-%s", name, request.Spec.SecretRef.Namespace, request.Spec.SecretRef.Name)
+	if len(roleName) > 64 {
+		roleName = roleName[:64]
+	}
+	return roleName
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DryRunOutput holds the AWS resources described by `ccoctl aws create-all --dry-run`
+type DryRunOutput struct {
+	Bucket           string
+	IssuerURL        string
+	IdentityProvider *DryRunIdentityProvider
+	Roles            []DryRunRole
+	// Files maps each parsed resource file to its kind: bucket, identity-provider, role or policy
+	Files map[string]string
+	// Discovery maps the OIDC discovery documents to their object key in the bucket
+	Discovery map[string]string
+	// SigningKey is the generated private key matching the published keys
+	SigningKey string
+}
+
+// DryRunIdentityProvider is the IAM OIDC identity provider
+type DryRunIdentityProvider struct {
+	URL         string   `json:"Url"`
+	ClientIDs   []string `json:"ClientIDList"`
+	Thumbprints []string `json:"ThumbprintList"`
+}
+
+// DryRunRole is an IAM role with its trust policy and inline policies
+type DryRunRole struct {
+	Name        string
+	Description string
+	TrustPolicy string
+	Policies    []DryRunPolicy
+}
+
+// DryRunPolicy is an inline role policy
+type DryRunPolicy struct {
+	Name     string
+	Document string
+}
+
+// Resource file kinds
+const (
+	DryRunBucketFile           = "bucket"
+	DryRunIdentityProviderFile = "identity-provider"
+	DryRunRoleFile             = "role"
+	DryRunPolicyFile           = "policy"
+)
+
+// ParseDryRunOutput reads the JSON files written by ccoctl in dry-run mode.
+// Files are recognised by their content, since file names differ between
+// ccoctl versions.
+func ParseDryRunOutput(dir string) (*DryRunOutput, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	out := &DryRunOutput{Files: map[string]string{}, Discovery: map[string]string{}}
+	roles := map[string]*DryRunRole{}
+	var roleOrder []string
+	role := func(name string) *DryRunRole {
+		if _, ok := roles[name]; !ok {
+			roles[name] = &DryRunRole{Name: name}
+			roleOrder = append(roleOrder, name)
+		}
+		return roles[name]
+	}
+
+	for _, path := range paths {
+		if !FileExists(path) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(content, &fields) != nil {
+			continue
+		}
+
+		switch {
+		case fields["AssumeRolePolicyDocument"] != nil:
+			var input struct {
+				RoleName                 string
+				Description              string
+				AssumeRolePolicyDocument string
+			}
+			if err := json.Unmarshal(content, &input); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			r := role(input.RoleName)
+			r.Description = input.Description
+			r.TrustPolicy = input.AssumeRolePolicyDocument
+			out.Files[path] = DryRunRoleFile
+		case fields["PolicyDocument"] != nil:
+			var input struct {
+				RoleName       string
+				PolicyName     string
+				PolicyDocument string
+			}
+			if err := json.Unmarshal(content, &input); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			r := role(input.RoleName)
+			r.Policies = append(r.Policies, DryRunPolicy{Name: input.PolicyName, Document: input.PolicyDocument})
+			out.Files[path] = DryRunPolicyFile
+		case fields["ClientIDList"] != nil:
+			provider := &DryRunIdentityProvider{}
+			if err := json.Unmarshal(content, provider); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			out.IdentityProvider = provider
+			out.Files[path] = DryRunIdentityProviderFile
+		case fields["Bucket"] != nil:
+			var input struct{ Bucket string }
+			if err := json.Unmarshal(content, &input); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			out.Bucket = input.Bucket
+			out.Files[path] = DryRunBucketFile
+		case fields["issuer"] != nil:
+			var config struct {
+				Issuer string `json:"issuer"`
+			}
+			if err := json.Unmarshal(content, &config); err == nil {
+				out.IssuerURL = config.Issuer
+			}
+			out.Discovery[".well-known/openid-configuration"] = string(content)
+		case fields["keys"] != nil:
+			out.Discovery["keys.json"] = string(content)
+		}
+	}
+
+	if key := filepath.Join(dir, "serviceaccount-signer.private"); FileExists(key) {
+		out.SigningKey = key
+	}
+
+	for _, name := range roleOrder {
+		out.Roles = append(out.Roles, *roles[name])
+	}
+
+	if out.IssuerURL == "" && out.IdentityProvider != nil {
+		out.IssuerURL = out.IdentityProvider.URL
+		if !strings.HasPrefix(out.IssuerURL, "https://") {
+			out.IssuerURL = "https://" + out.IssuerURL
+		}
+	}
+
+	return out, nil
+}
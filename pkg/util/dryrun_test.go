@@ -0,0 +1,55 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDryRunOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"01-bucket.json":             `{"Bucket":"test-cluster-oidc"}`,
+		"02-openid-configuration":    `{"issuer":"https://test-cluster-oidc.s3.us-east-2.amazonaws.com"}`,
+		"03-iam-identity-provider":   `{"Url":"https://test-cluster-oidc.s3.us-east-2.amazonaws.com","ClientIDList":["openshift","sts.amazonaws.com"],"ThumbprintList":["abc"]}`,
+		"04-iam-role-ingress":        `{"RoleName":"test-cluster-openshift-ingress","Description":"ingress role","AssumeRolePolicyDocument":"{\"Version\":\"2012-10-17\"}"}`,
+		"05-iam-role-policy-ingress": `{"RoleName":"test-cluster-openshift-ingress","PolicyName":"test-cluster-openshift-ingress","PolicyDocument":"{\"Statement\":[]}"}`,
+		"serviceaccount-signer.key":  "not json",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := ParseDryRunOutput(dir)
+	if err != nil {
+		t.Fatalf("ParseDryRunOutput failed: %v", err)
+	}
+
+	if out.Bucket != "test-cluster-oidc" {
+		t.Errorf("Expected bucket test-cluster-oidc, got %q", out.Bucket)
+	}
+	if out.IssuerURL != "https://test-cluster-oidc.s3.us-east-2.amazonaws.com" {
+		t.Errorf("Unexpected issuer URL %q", out.IssuerURL)
+	}
+	if out.IdentityProvider == nil || len(out.IdentityProvider.ClientIDs) != 2 {
+		t.Errorf("Unexpected identity provider %+v", out.IdentityProvider)
+	}
+	if len(out.Roles) != 1 {
+		t.Fatalf("Expected 1 role, got %d", len(out.Roles))
+	}
+	role := out.Roles[0]
+	if role.Name != "test-cluster-openshift-ingress" || role.TrustPolicy == "" || len(role.Policies) != 1 {
+		t.Errorf("Unexpected role %+v", role)
+	}
+	if out.Files[filepath.Join(dir, "04-iam-role-ingress")] != DryRunRoleFile {
+		t.Errorf("Role file not classified, got %v", out.Files)
+	}
+	if out.Discovery[".well-known/openid-configuration"] == "" {
+		t.Errorf("Expected the openid configuration as discovery document, got %v", out.Discovery)
+	}
+	if _, ok := out.Files[filepath.Join(dir, "serviceaccount-signer.key")]; ok {
+		t.Error("Non-JSON files should be ignored")
+	}
+}
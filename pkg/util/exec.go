@@ -27,7 +27,7 @@ func (e *RealExecutor) Execute(name string, args ...string) (string, error) {
 
 func (e *RealExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
 	cmd := exec.Command(name, args...)
-	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = MergeEnv(os.Environ(), env)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
@@ -58,7 +58,7 @@ func (e *RealExecutor) ExecuteInteractiveWithEnv(name string, env []string, args
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = MergeEnv(os.Environ(), env)
 	// Set process group to allow proper signal handling and terminal control
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: false,
@@ -68,14 +68,16 @@ func (e *RealExecutor) ExecuteInteractiveWithEnv(name string, env []string, args
 
 // MockExecutor is a mock executor for testing
 type MockExecutor struct {
-	Commands []string          // Records all executed commands
-	Outputs  map[string]string // Map of command -> output
-	Errors   map[string]error  // Map of command -> error
+	Commands []string            // Records all executed commands
+	Envs     map[string][]string // Map of command -> extra environment of its last run
+	Outputs  map[string]string   // Map of command -> output
+	Errors   map[string]error    // Map of command -> error
 }
 
 func NewMockExecutor() *MockExecutor {
 	return &MockExecutor{
 		Commands: []string{},
+		Envs:     make(map[string][]string),
 		Outputs:  make(map[string]string),
 		Errors:   make(map[string]error),
 	}
@@ -99,6 +101,7 @@ func (e *MockExecutor) Execute(name string, args ...string) (string, error) {
 func (e *MockExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
 	cmdStr := name + " " + strings.Join(args, " ")
 	e.Commands = append(e.Commands, cmdStr)
+	e.Envs[cmdStr] = env
 
 	if err, ok := e.Errors[cmdStr]; ok {
 		return "", err
@@ -137,6 +140,18 @@ func (e *MockExecutor) WasExecutedContaining(substring string) bool {
 	return false
 }
 
+// EnvContaining returns the extra environment of every executed command
+// containing substring
+func (e *MockExecutor) EnvContaining(substring string) [][]string {
+	var envs [][]string
+	for _, c := range e.Commands {
+		if strings.Contains(c, substring) {
+			envs = append(envs, e.Envs[c])
+		}
+	}
+	return envs
+}
+
 func (e *MockExecutor) ExecuteInteractive(name string, args ...string) error {
 	cmdStr := name + " " + strings.Join(args, " ")
 	e.Commands = append(e.Commands, cmdStr)
@@ -151,6 +166,7 @@ func (e *MockExecutor) ExecuteInteractive(name string, args ...string) error {
 func (e *MockExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
 	cmdStr := name + " " + strings.Join(args, " ")
 	e.Commands = append(e.Commands, cmdStr)
+	e.Envs[cmdStr] = env
 
 	if err, ok := e.Errors[cmdStr]; ok {
 		return err
@@ -183,6 +199,18 @@ func RunCommandWithEnv(executor CommandExecutor, env []string, name string, args
 	return nil
 }
 
+// RunCommandWithEnvOutput runs a command with additional environment variables and returns its output
+func RunCommandWithEnvOutput(executor CommandExecutor, env []string, name string, args ...string) (string, error) {
+	output, err := executor.ExecuteWithEnv(name, env, args...)
+	if err != nil {
+		if output != "" {
+			return output, fmt.Errorf("command failed: %s %v: %w\nOutput: %s", name, args, err, strings.TrimSpace(output))
+		}
+		return output, fmt.Errorf("command failed: %s %v: %w", name, args, err)
+	}
+	return output, nil
+}
+
 // RunInteractiveCommand runs a command with stdin/stdout/stderr connected to terminal
 func RunInteractiveCommand(executor CommandExecutor, name string, args ...string) error {
 	if err := executor.ExecuteInteractive(name, args...); err != nil {
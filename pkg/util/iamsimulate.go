@@ -0,0 +1,48 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PolicySourceARN returns the IAM principal whose policies apply to a caller
+// identity: the user itself, or the role of an assumed-role session. The
+// role is looked up since the session ARN drops its path.
+func PolicySourceARN(executor CommandExecutor, env []string, callerARN string) (string, error) {
+	parts := strings.SplitN(callerARN, ":", 6)
+	if len(parts) != 6 {
+		return "", fmt.Errorf("unexpected caller ARN %q", callerARN)
+	}
+	service, resource := parts[2], parts[5]
+	switch {
+	case service == "iam" && strings.HasPrefix(resource, "user/"):
+		return callerARN, nil
+	case service == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		roleName := strings.SplitN(strings.TrimPrefix(resource, "assumed-role/"), "/", 2)[0]
+		output, err := RunAWS(executor, env, "iam", "get-role", "--role-name", roleName, "--query", "Role.Arn")
+		if err != nil {
+			return "", fmt.Errorf("failed to look up role %s: %w", roleName, err)
+		}
+		var roleARN string
+		if err := parseAWSOutput(output, &roleARN); err != nil || roleARN == "" {
+			return "", fmt.Errorf("failed to parse aws iam get-role output %q", output)
+		}
+		return roleARN, nil
+	}
+	return "", fmt.Errorf("the policies of %s cannot be simulated, only IAM users and assumed roles can", callerARN)
+}
+
+// SimulatePrincipalPolicy returns the actions the policies of the principal
+// do not allow, on any resource
+func SimulatePrincipalPolicy(executor CommandExecutor, env []string, principalARN string, actions []string) ([]string, error) {
+	args := append([]string{"iam", "simulate-principal-policy", "--policy-source-arn", principalARN, "--action-names"}, actions...)
+	output, err := RunAWS(executor, env, append(args, "--query", "EvaluationResults[?EvalDecision!='allowed'].EvalActionName")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate the policies of %s: %w", principalARN, err)
+	}
+	var denied []string
+	if err := parseAWSOutput(output, &denied); err != nil {
+		return nil, fmt.Errorf("failed to parse aws iam simulate-principal-policy output: %w", err)
+	}
+	return denied, nil
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicySourceARN(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam get-role --role-name AWSReservedSSO_Admin_0123 --query Role.Arn --output json",
+		`"arn:aws:iam::123456789012:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_Admin_0123"`)
+
+	tests := map[string]string{
+		"arn:aws:iam::123456789012:user/ci/installer":                                       "arn:aws:iam::123456789012:user/ci/installer",
+		"arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Admin_0123/jdoe@example.com": "arn:aws:iam::123456789012:role/aws-reserved/sso.amazonaws.com/AWSReservedSSO_Admin_0123",
+	}
+	for caller, want := range tests {
+		got, err := PolicySourceARN(executor, nil, caller)
+		if err != nil || got != want {
+			t.Errorf("PolicySourceARN(%s) = %s, %v; expected %s", caller, got, err, want)
+		}
+	}
+	if _, err := PolicySourceARN(executor, nil, "arn:aws:iam::123456789012:root"); err == nil {
+		t.Error("Expected an error for the root user")
+	}
+}
+
+func TestSimulatePrincipalPolicy(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam simulate-principal-policy --policy-source-arn arn:aws:iam::123456789012:user/installer "+
+		"--action-names iam:CreateRole iam:TagRole s3:CreateBucket --query EvaluationResults[?EvalDecision!='allowed'].EvalActionName --output json",
+		`["iam:TagRole"]`)
+
+	denied, err := SimulatePrincipalPolicy(executor, nil, "arn:aws:iam::123456789012:user/installer",
+		[]string{"iam:CreateRole", "iam:TagRole", "s3:CreateBucket"})
+	if err != nil {
+		t.Fatalf("SimulatePrincipalPolicy failed: %v", err)
+	}
+	if strings.Join(denied, ",") != "iam:TagRole" {
+		t.Errorf("Expected iam:TagRole denied, got %v", denied)
+	}
+}
@@ -9,14 +9,68 @@ import (
 
 // InstallConfig represents the minimal structure we need from install-config.yaml
 type InstallConfig struct {
+	BaseDomain string `yaml:"baseDomain"`
+	// Publish is External (default) or Internal
+	Publish  string `yaml:"publish"`
 	Metadata struct {
 		Name string `yaml:"name"`
 	} `yaml:"metadata"`
 	Platform struct {
 		AWS struct {
 			Region string `yaml:"region"`
+			// Subnets are set when installing into an existing VPC
+			Subnets []string `yaml:"subnets"`
+			// HostedZone and HostedZoneRole select a private zone of another account
+			HostedZone     string `yaml:"hostedZone"`
+			HostedZoneRole string `yaml:"hostedZoneRole"`
 		} `yaml:"aws"`
 	} `yaml:"platform"`
+	Networking struct {
+		MachineNetwork []struct {
+			CIDR string `yaml:"cidr"`
+		} `yaml:"machineNetwork"`
+		ClusterNetwork []struct {
+			CIDR string `yaml:"cidr"`
+		} `yaml:"clusterNetwork"`
+		ServiceNetwork []string `yaml:"serviceNetwork"`
+	} `yaml:"networking"`
+	ControlPlane MachinePool   `yaml:"controlPlane"`
+	Compute      []MachinePool `yaml:"compute"`
+}
+
+// MachinePool is a controlPlane or compute pool of install-config.yaml
+type MachinePool struct {
+	Name string `yaml:"name"`
+	// Replicas is nil when left to the openshift-install default
+	Replicas *int `yaml:"replicas"`
+	Platform struct {
+		AWS struct {
+			Type  string   `yaml:"type"`
+			Zones []string `yaml:"zones"`
+		} `yaml:"aws"`
+	} `yaml:"platform"`
+}
+
+// MachinePools returns the controlPlane pool followed by the compute pools
+func (c *InstallConfig) MachinePools() []MachinePool {
+	pools := []MachinePool{c.ControlPlane}
+	if pools[0].Name == "" {
+		pools[0].Name = "master"
+	}
+	return append(pools, c.Compute...)
+}
+
+// NetworkCIDRs returns the machine, cluster and service network CIDRs
+func (c *InstallConfig) NetworkCIDRs() []string {
+	var cidrs []string
+	for _, n := range c.Networking.MachineNetwork {
+		cidrs = append(cidrs, n.CIDR)
+	}
+	for _, n := range c.Networking.ClusterNetwork {
+		cidrs = append(cidrs, n.CIDR)
+	}
+	cidrs = append(cidrs, c.Networking.ServiceNetwork...)
+	return cidrs
 }
 
 // ReadInstallConfig reads and parses install-config.yaml
@@ -34,6 +88,16 @@ func ReadInstallConfig(path string) (*InstallConfig, error) {
 	return &config, nil
 }
 
+// LoadInstallConfig reads install-config.yaml of a version, or the backup
+// taken before Step 6 consumed it
+func LoadInstallConfig(versionArch string) (*InstallConfig, error) {
+	path := GetInstallConfigPath(versionArch)
+	if !FileExists(path) && FileExists(path+".backup") {
+		path += ".backup"
+	}
+	return ReadInstallConfig(path)
+}
+
 // ExtractClusterNameAndRegion reads install-config.yaml and returns the cluster name and region
 func ExtractClusterNameAndRegion(installConfigPath string) (clusterName string, region string, err error) {
 	config, err := ReadInstallConfig(installConfigPath)
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// InventoryFile is the inventory of created cloud resources, at the workspace root
+const InventoryFile = "inventory.json"
+
+// Inventory records the cloud resources created by an installation
+type Inventory struct {
+	ClusterName  string `json:"clusterName"`
+	Region       string `json:"region"`
+	ReleaseImage string `json:"releaseImage,omitempty"`
+	// Created in Step 7
+	IAMRoles               []string `json:"iamRoles,omitempty"`
+	OIDCProviderARN        string   `json:"oidcProviderARN,omitempty"`
+	S3Bucket               string   `json:"s3Bucket,omitempty"`
+	CloudFrontDistribution string   `json:"cloudFrontDistribution,omitempty"`
+	// Created in Step 10
+	InfraID   string `json:"infraID,omitempty"`
+	ClusterID string `json:"clusterID,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LoadInventory reads the inventory, returning an empty one if the file does not exist
+func LoadInventory(path string) (*Inventory, error) {
+	inventory := &Inventory{}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inventory, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	if err := json.Unmarshal(content, inventory); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	return inventory, nil
+}
+
+// Records tells whether the inventory belongs to the installation of the
+// cluster with the release image. Empty values match anything.
+func (i *Inventory) Records(clusterName, releaseImage string) bool {
+	if clusterName != "" && i.ClusterName != "" && clusterName != i.ClusterName {
+		return false
+	}
+	return releaseImage == "" || i.ReleaseImage == "" || releaseImage == i.ReleaseImage
+}
+
+// RecordsInstallation tells whether the inventory belongs to the
+// installation, by cluster name and, when both are known, release
+func (i *Inventory) RecordsInstallation(installation *Installation) bool {
+	if i.ClusterName != installation.ClusterName {
+		return false
+	}
+	if i.ReleaseImage == "" || installation.VersionArch == "" {
+		return true
+	}
+	versionArch, err := ExtractVersionArch(i.ReleaseImage)
+	return err != nil || versionArch == installation.VersionArch
+}
+
+// RemoveInventory deletes the inventory when it records the installation
+func RemoveInventory(path string, installation *Installation) error {
+	inventory, err := LoadInventory(path)
+	if err != nil {
+		return err
+	}
+	if !inventory.RecordsInstallation(installation) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove inventory: %w", err)
+	}
+	return nil
+}
+
+// Save writes the inventory
+func (i *Inventory) Save(path string) error {
+	i.UpdatedAt = time.Now().UTC()
+	content, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// ClusterMetadata is the part of metadata.json written by openshift-install that we need
+type ClusterMetadata struct {
+	ClusterName string `json:"clusterName"`
+	ClusterID   string `json:"clusterID"`
+	InfraID     string `json:"infraID"`
+	AWS         struct {
+		Region string `json:"region"`
+	} `json:"aws"`
+}
+
+// ReadClusterMetadata reads metadata.json from the installation directory
+func ReadClusterMetadata(path string) (*ClusterMetadata, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &ClusterMetadata{}
+	if err := json.Unmarshal(content, metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return metadata, nil
+}
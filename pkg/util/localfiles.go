@@ -0,0 +1,254 @@
+package util
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workspaceDirs are the directories ccoctl and the manifest copy of older
+// releases wrote at the workspace root, shared by every installation there
+var workspaceDirs = []string{"manifests", "tls", "_output"}
+
+// LocalFiles returns the files of the installation left in the workspace:
+// the version directory except its binaries, which other clusters may still
+// use, and the manifests/, tls/ and _output/ directories of the workspace
+// unless it holds other installations
+func (i *Installation) LocalFiles() ([]string, error) {
+	var paths []string
+	if i.VersionArch != "" {
+		entries, err := os.ReadDir(i.VersionDir())
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Name() == "bin" {
+				continue
+			}
+			paths = append(paths, filepath.Join(i.VersionDir(), entry.Name()))
+		}
+	}
+	shared, err := i.WorkspaceShared()
+	if err != nil {
+		return nil, err
+	}
+	if !shared {
+		paths = append(paths, i.workspaceDirs()...)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// WorkspaceShared reports whether the workspace holds other installations,
+// whose keys and manifests its workspace-level directories may hold
+func (i *Installation) WorkspaceShared() (bool, error) {
+	installations, err := FindInstallations(i.Workspace)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range installations {
+		if other.VersionArch != i.VersionArch {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SkippedWorkspaceDirs returns the workspace-level directories LocalFiles
+// leaves out because the workspace holds other installations
+func (i *Installation) SkippedWorkspaceDirs() ([]string, error) {
+	shared, err := i.WorkspaceShared()
+	if err != nil || !shared {
+		return nil, err
+	}
+	return i.workspaceDirs(), nil
+}
+
+func (i *Installation) workspaceDirs() []string {
+	var paths []string
+	for _, dir := range workspaceDirs {
+		path := filepath.Join(i.Workspace, dir)
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ArchiveFiles returns the files worth keeping once the installation is
+// gone: the installer log, the install-config backup, metadata.json and the
+// manifests generated by ccoctl
+func (i *Installation) ArchiveFiles() ([]string, error) {
+	var files []string
+	for _, path := range []string{
+		filepath.Join(i.VersionDir(), ".openshift_install.log"),
+		filepath.Join(i.Workspace, ".openshift_install.log"),
+		filepath.Join(i.VersionDir(), "install-config.yaml.backup"),
+		filepath.Join(i.VersionDir(), "metadata.json"),
+	} {
+		if i.VersionArch != "" && FileExists(path) {
+			files = append(files, path)
+		}
+	}
+	manifests, err := filepath.Glob(filepath.Join(i.VersionDir(), "_output", "manifests", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(manifests)
+	for _, path := range manifests {
+		if FileExists(path) {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// ArchiveLocalFiles writes the archive files to a new tar.gz, readable only
+// by the owner since install-config.yaml holds the pull secret. Entries are
+// named relative to the workspace. The archive must not be among the files
+// a purge would delete.
+func (i *Installation) ArchiveLocalFiles(archive string) ([]string, error) {
+	dest, err := filepath.Abs(archive)
+	if err != nil {
+		return nil, err
+	}
+	local, err := i.LocalFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range local {
+		if abs, err := filepath.Abs(path); err == nil && isWithin(abs, dest) {
+			return nil, fmt.Errorf("%s is inside %s, which a purge deletes; choose a path outside of it", dest, path)
+		}
+	}
+
+	files, err := i.ArchiveFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("nothing to archive in %s", i.VersionDir())
+	}
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = writeTarFiles(tw, i.Workspace, files)
+	for _, closer := range []io.Closer{tw, gz, f} {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		os.Remove(dest)
+		return nil, err
+	}
+	return files, nil
+}
+
+func writeTarFiles(tw *tar.Writer, workspace string, files []string) error {
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		content, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, content)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// PurgeLocalFiles securely deletes the local files of the installation and
+// returns the removed paths
+func (i *Installation) PurgeLocalFiles() ([]string, error) {
+	paths, err := i.LocalFiles()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, path := range paths {
+		if err := SecureRemoveAll(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// SecureRemoveAll overwrites every regular file under path with zeros before
+// removing it, so that private keys and kubeconfigs are not left in freed
+// blocks. Symbolic links are removed, never followed.
+func SecureRemoveAll(path string) error {
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return overwriteFile(file, info.Size())
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to overwrite %s: %w", path, err)
+	}
+	return os.RemoveAll(path)
+}
+
+func overwriteFile(path string, size int64) error {
+	// Keys are often read-only
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(f, zeroReader{}, size)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,170 @@
+package util
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeInstallationFiles(t *testing.T) *Installation {
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, "artifacts", "4.12.0-x86_64")
+	for _, sub := range []string{"bin", "auth", "credreqs", filepath.Join("_output", "manifests"), filepath.Join("_output", "tls")} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+	}
+	os.MkdirAll(filepath.Join(workspace, "manifests"), 0755)
+	os.MkdirAll(filepath.Join(workspace, "tls"), 0755)
+	os.MkdirAll(filepath.Join(workspace, "artifacts", "bin"), 0755)
+
+	files := map[string]string{
+		filepath.Join(dir, "bin", "openshift-install"):                                      "binary",
+		filepath.Join(dir, "auth", "kubeconfig"):                                            "kubeconfig",
+		filepath.Join(dir, ".openshift_install.log"):                                        "log",
+		filepath.Join(dir, "install-config.yaml.backup"):                                    "metadata:\n  name: dev\n",
+		filepath.Join(dir, "metadata.json"):                                                 `{"infraID":"dev-x7k2p"}`,
+		filepath.Join(dir, "_output", "manifests", "cluster-authentication-02-config.yaml"): "issuer",
+		filepath.Join(dir, "_output", "tls", "bound-service-account-signing-key.key"):       "private key",
+		filepath.Join(workspace, "tls", "bound-service-account-signing-key.key"):            "private key",
+		filepath.Join(workspace, "manifests", "cluster-authentication-02-config.yaml"):      "issuer",
+		filepath.Join(workspace, "artifacts", "bin", "ccoctl"):                              "binary",
+	}
+	for path, content := range files {
+		os.WriteFile(path, []byte(content), 0600)
+	}
+	os.Chmod(filepath.Join(dir, "_output", "tls", "bound-service-account-signing-key.key"), 0400)
+
+	return &Installation{Workspace: workspace, VersionArch: "4.12.0-x86_64", ClusterName: "dev", Region: "us-east-2"}
+}
+
+func TestArchiveLocalFiles(t *testing.T) {
+	installation := writeInstallationFiles(t)
+	archive := filepath.Join(t.TempDir(), "dev.tar.gz")
+
+	if _, err := installation.ArchiveLocalFiles(archive); err != nil {
+		t.Fatalf("ArchiveLocalFiles failed: %v", err)
+	}
+	info, err := os.Stat(archive)
+	if err != nil {
+		t.Fatalf("Expected archive: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected archive mode 0600, got %v", info.Mode().Perm())
+	}
+
+	f, _ := os.Open(archive)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Expected gzip archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	want := []string{
+		"artifacts/4.12.0-x86_64/.openshift_install.log",
+		"artifacts/4.12.0-x86_64/install-config.yaml.backup",
+		"artifacts/4.12.0-x86_64/metadata.json",
+		"artifacts/4.12.0-x86_64/_output/manifests/cluster-authentication-02-config.yaml",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected entries %v, got %v", want, names)
+	}
+
+	// An existing archive is never overwritten
+	if _, err := installation.ArchiveLocalFiles(archive); err == nil {
+		t.Error("Expected an error for an existing archive")
+	}
+
+	// Nor written where a purge would delete it
+	inside := filepath.Join(installation.VersionDir(), "auth", "dev.tar.gz")
+	if _, err := installation.ArchiveLocalFiles(inside); err == nil || !strings.Contains(err.Error(), "purge") {
+		t.Errorf("Expected an error for an archive inside the installation files, got %v", err)
+	}
+}
+
+func TestPurgeLocalFiles(t *testing.T) {
+	installation := writeInstallationFiles(t)
+
+	removed, err := installation.PurgeLocalFiles()
+	if err != nil {
+		t.Fatalf("PurgeLocalFiles failed: %v", err)
+	}
+	if len(removed) != 8 {
+		t.Errorf("Expected 8 removed paths, got %v", removed)
+	}
+
+	for _, path := range []string{
+		filepath.Join(installation.VersionDir(), "auth"),
+		filepath.Join(installation.VersionDir(), "_output"),
+		filepath.Join(installation.VersionDir(), "metadata.json"),
+		filepath.Join(installation.Workspace, "manifests"),
+		filepath.Join(installation.Workspace, "tls"),
+	} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(installation.VersionDir(), "bin", "openshift-install"),
+		filepath.Join(installation.Workspace, "artifacts", "bin", "ccoctl"),
+	} {
+		if !FileExists(path) {
+			t.Errorf("Expected binary %s to be kept", path)
+		}
+	}
+}
+
+func TestPurgeLocalFilesSharedWorkspace(t *testing.T) {
+	installation := writeInstallationFiles(t)
+	other := filepath.Join(installation.Workspace, "artifacts", "4.13.0-x86_64")
+	os.MkdirAll(other, 0755)
+	os.WriteFile(filepath.Join(other, "metadata.json"), []byte(`{"clusterName":"prod","infraID":"prod-a1b2c"}`), 0644)
+
+	if _, err := installation.PurgeLocalFiles(); err != nil {
+		t.Fatalf("PurgeLocalFiles failed: %v", err)
+	}
+	// The workspace-level keys and manifests may belong to the other cluster
+	for _, path := range []string{
+		filepath.Join(installation.Workspace, "manifests"),
+		filepath.Join(installation.Workspace, "tls", "bound-service-account-signing-key.key"),
+		filepath.Join(other, "metadata.json"),
+	} {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("Expected %s to be kept: %v", path, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(installation.VersionDir(), "auth")); !os.IsNotExist(err) {
+		t.Error("Expected the files of the installation to be removed")
+	}
+	skipped, err := installation.SkippedWorkspaceDirs()
+	if err != nil || len(skipped) != 2 {
+		t.Errorf("Expected manifests/ and tls/ to be reported as kept, got %v (%v)", skipped, err)
+	}
+}
+
+func TestSecureRemoveAllSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "outside")
+	os.WriteFile(target, []byte("keep me"), 0644)
+	purged := filepath.Join(dir, "purged")
+	os.MkdirAll(purged, 0755)
+	os.Symlink(target, filepath.Join(purged, "link"))
+
+	if err := SecureRemoveAll(purged); err != nil {
+		t.Fatalf("SecureRemoveAll failed: %v", err)
+	}
+	content, err := os.ReadFile(target)
+	if err != nil || string(content) != "keep me" {
+		t.Errorf("Expected the symlink target to be left untouched, got %q, %v", content, err)
+	}
+}
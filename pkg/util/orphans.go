@@ -0,0 +1,296 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Orphan is a cloud resource of the cluster still present after cleanup
+type Orphan struct {
+	Kind string
+	ID   string
+	// Remove lists the commands that delete the resource
+	Remove []string
+}
+
+// Orphan kinds
+const (
+	OrphanIAMRole                = "IAM role"
+	OrphanOIDCProvider           = "OIDC provider"
+	OrphanS3Bucket               = "S3 bucket"
+	OrphanCloudFrontDistribution = "CloudFront distribution"
+	OrphanTaggedResource         = "tagged resource"
+)
+
+// FindOrphans queries AWS through the aws CLI for resources of the
+// installation that cleanup left behind. withIAM is false when the IAM roles
+// and the issuer are not ours (byoIAM). Tagged resources are only searched
+// when the infraID is known. The IAM resources, bucket and distribution are
+// looked up with iamEnv, the tagged resources with infraEnv.
+func FindOrphans(executor CommandExecutor, iamEnv, infraEnv []string, i *Installation, withIAM bool) ([]Orphan, error) {
+	var orphans []Orphan
+	if withIAM {
+		for _, find := range []func(CommandExecutor, []string, *Installation) ([]Orphan, error){
+			findOrphanRoles, findOrphanOIDCProviders, findOrphanBucket, findOrphanDistributions,
+		} {
+			found, err := find(executor, iamEnv, i)
+			if err != nil {
+				return nil, err
+			}
+			orphans = append(orphans, found...)
+		}
+	}
+	if i.InfraID != "" {
+		found, err := findOrphanTaggedResources(executor, infraEnv, i)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, found...)
+	}
+	return orphans, nil
+}
+
+// findOrphanRoles lists the IAM roles of the cluster: the ccoctl roles, by
+// their exact names, and the roles openshift-install names after the
+// infraID. A <cluster>- prefix would also match the roles of other clusters,
+// such as ci-2 for ci.
+func findOrphanRoles(executor CommandExecutor, env []string, i *Installation) ([]Orphan, error) {
+	ccoctlRoles, err := ccoctlRoleNames(i)
+	if err != nil {
+		return nil, err
+	}
+	if len(ccoctlRoles) == 0 && i.InfraID == "" {
+		return nil, nil
+	}
+
+	output, err := RunAWS(executor, env, "iam", "list-roles", "--query", "Roles[].RoleName")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IAM roles: %w", err)
+	}
+	var names []string
+	if err := parseAWSOutput(output, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse aws iam list-roles output: %w", err)
+	}
+
+	var orphans []Orphan
+	for _, name := range names {
+		if !slices.Contains(ccoctlRoles, name) && (i.InfraID == "" || !strings.HasPrefix(name, i.InfraID+"-")) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind: OrphanIAMRole,
+			ID:   name,
+			Remove: []string{
+				fmt.Sprintf("for policy in $(aws iam list-role-policies --role-name %s --query PolicyNames --output text); do aws iam delete-role-policy --role-name %s --policy-name $policy; done", name, name),
+				fmt.Sprintf("for profile in $(aws iam list-instance-profiles-for-role --role-name %s --query 'InstanceProfiles[].InstanceProfileName' --output text); do aws iam remove-role-from-instance-profile --instance-profile-name $profile --role-name %s; done", name, name),
+				fmt.Sprintf("aws iam delete-role --role-name %s", name),
+			},
+		})
+	}
+	return orphans, nil
+}
+
+// ccoctlRoleNames returns the names of the ccoctl roles of the installation,
+// from the inventory and from the credentials requests
+func ccoctlRoleNames(i *Installation) ([]string, error) {
+	var names []string
+	inventory, err := LoadInventory(filepath.Join(i.Workspace, InventoryFile))
+	if err != nil {
+		return nil, err
+	}
+	if inventory.RecordsInstallation(i) {
+		for _, arn := range inventory.IAMRoles {
+			names = append(names, RoleNameFromARN(arn))
+		}
+	}
+	if i.VersionArch != "" {
+		requests, err := ReadCredentialsRequests(filepath.Join(i.VersionDir(), "credreqs"))
+		if err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			if name := CcoctlRoleName(i.ClusterName, request); !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// findOrphanOIDCProviders lists the IAM OIDC providers for the cluster issuer
+func findOrphanOIDCProviders(executor CommandExecutor, env []string, i *Installation) ([]Orphan, error) {
+	output, err := RunAWS(executor, env, "iam", "list-open-id-connect-providers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OIDC providers: %w", err)
+	}
+	var parsed struct {
+		OpenIDConnectProviderList []struct {
+			Arn string `json:"Arn"`
+		} `json:"OpenIDConnectProviderList"`
+	}
+	if err := parseAWSOutput(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws iam list-open-id-connect-providers output: %w", err)
+	}
+
+	var orphans []Orphan
+	for _, provider := range parsed.OpenIDConnectProviderList {
+		if !strings.HasSuffix(provider.Arn, ":oidc-provider/"+i.IssuerHost()) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind:   OrphanOIDCProvider,
+			ID:     provider.Arn,
+			Remove: []string{fmt.Sprintf("aws iam delete-open-id-connect-provider --open-id-connect-provider-arn %s", provider.Arn)},
+		})
+	}
+	return orphans, nil
+}
+
+// FindOIDCProviderARN looks up the OIDC provider of the issuer of the
+// installation
+func FindOIDCProviderARN(executor CommandExecutor, env []string, i *Installation) (string, error) {
+	providers, err := findOrphanOIDCProviders(executor, env, i)
+	if err != nil {
+		return "", err
+	}
+	if len(providers) == 0 {
+		return "", fmt.Errorf("no OIDC provider found for %s", i.IssuerHost())
+	}
+	return providers[0].ID, nil
+}
+
+// findOrphanBucket checks whether the OIDC bucket still exists
+func findOrphanBucket(executor CommandExecutor, env []string, i *Installation) ([]Orphan, error) {
+	bucket := OIDCBucketName(i.ClusterName)
+	if _, err := RunAWS(executor, env, "s3api", "head-bucket", "--bucket", bucket); err != nil {
+		// head-bucket has no body, a missing bucket is reported as a 404
+		if strings.Contains(err.Error(), "(404)") || strings.Contains(err.Error(), "Not Found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up S3 bucket %s: %w", bucket, err)
+	}
+	return []Orphan{{
+		Kind:   OrphanS3Bucket,
+		ID:     bucket,
+		Remove: []string{fmt.Sprintf("aws s3 rb s3://%s --force", bucket)},
+	}}, nil
+}
+
+// findOrphanDistributions lists the CloudFront distributions serving the
+// issuer or reading from the OIDC bucket
+func findOrphanDistributions(executor CommandExecutor, env []string, i *Installation) ([]Orphan, error) {
+	distributions, err := listDistributions(executor, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Orphan
+	for _, distribution := range distributions {
+		if distribution.DomainName != i.IssuerHost() && !distribution.servesBucket(OIDCBucketName(i.ClusterName)) {
+			continue
+		}
+		id := distribution.Id
+		etag := fmt.Sprintf("$(aws cloudfront get-distribution-config --id %s --query ETag --output text)", id)
+		orphans = append(orphans, Orphan{
+			Kind: OrphanCloudFrontDistribution,
+			ID:   id,
+			Remove: []string{
+				fmt.Sprintf("aws cloudfront get-distribution-config --id %s --query DistributionConfig | sed 's/\"Enabled\": true/\"Enabled\": false/' > %s.json", id, id),
+				fmt.Sprintf("aws cloudfront update-distribution --id %s --distribution-config file://%s.json --if-match %s", id, id, etag),
+				fmt.Sprintf("aws cloudfront wait distribution-deployed --id %s", id),
+				fmt.Sprintf("aws cloudfront delete-distribution --id %s --if-match %s", id, etag),
+			},
+		})
+	}
+	return orphans, nil
+}
+
+// cloudFrontDistribution is a distribution listed by aws cloudfront list-distributions
+type cloudFrontDistribution struct {
+	Id         string `json:"Id"`
+	DomainName string `json:"DomainName"`
+	Origins    struct {
+		Items []struct {
+			DomainName string `json:"DomainName"`
+		} `json:"Items"`
+	} `json:"Origins"`
+}
+
+// servesBucket reports whether the distribution has the S3 bucket as origin
+func (d cloudFrontDistribution) servesBucket(bucket string) bool {
+	for _, origin := range d.Origins.Items {
+		if strings.HasPrefix(origin.DomainName, bucket+".s3.") {
+			return true
+		}
+	}
+	return false
+}
+
+func listDistributions(executor CommandExecutor, env []string) ([]cloudFrontDistribution, error) {
+	output, err := RunAWS(executor, env, "cloudfront", "list-distributions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CloudFront distributions: %w", err)
+	}
+	var parsed struct {
+		DistributionList struct {
+			Items []cloudFrontDistribution `json:"Items"`
+		} `json:"DistributionList"`
+	}
+	if err := parseAWSOutput(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws cloudfront list-distributions output: %w", err)
+	}
+	return parsed.DistributionList.Items, nil
+}
+
+// findOrphanTaggedResources lists the resources tagged as owned by the
+// cluster. They are all removed by openshift-install destroy, which only
+// needs a metadata.json naming the infraID.
+func findOrphanTaggedResources(executor CommandExecutor, env []string, i *Installation) ([]Orphan, error) {
+	tag := "kubernetes.io/cluster/" + i.InfraID
+	output, err := RunAWS(executor, env, "resourcegroupstaggingapi", "get-resources",
+		"--region", i.Region, "--tag-filters", "Key="+tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources tagged %s: %w", tag, err)
+	}
+	var parsed struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string `json:"ResourceARN"`
+		} `json:"ResourceTagMappingList"`
+	}
+	if err := parseAWSOutput(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws resourcegroupstaggingapi get-resources output: %w", err)
+	}
+
+	var remove []string
+	if !FileExists(filepath.Join(i.VersionDir(), "metadata.json")) {
+		metadata := fmt.Sprintf(`{"clusterName":"%s","infraID":"%s","aws":{"region":"%s","identifier":[{"%s":"owned"}]}}`,
+			i.ClusterName, i.InfraID, i.Region, tag)
+		remove = append(remove, fmt.Sprintf("mkdir -p %s && echo '%s' > %s/metadata.json", i.VersionDir(), metadata, i.VersionDir()))
+	}
+	remove = append(remove, fmt.Sprintf("%s destroy cluster --dir %s", i.Binary("openshift-install"), i.VersionDir()))
+
+	var arns []string
+	for _, resource := range parsed.ResourceTagMappingList {
+		arns = append(arns, resource.ResourceARN)
+	}
+	sort.Strings(arns)
+
+	var orphans []Orphan
+	for _, arn := range arns {
+		orphans = append(orphans, Orphan{Kind: OrphanTaggedResource, ID: arn, Remove: remove})
+	}
+	return orphans, nil
+}
+
+// parseAWSOutput decodes the JSON output of the aws CLI, where an empty
+// output means no result
+func parseAWSOutput(output string, v interface{}) error {
+	if strings.TrimSpace(output) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(output), v)
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	installation := &Installation{
+		Workspace:     t.TempDir(),
+		VersionArch:   "4.12.0-x86_64",
+		ClusterName:   "dev",
+		Region:        "us-east-2",
+		InfraID:       "dev-x7k2p",
+		Issuer:        "https://d1234abcd.cloudfront.net",
+		PrivateBucket: true,
+	}
+
+	credReqsDir := filepath.Join(installation.VersionDir(), "credreqs")
+	os.MkdirAll(credReqsDir, 0755)
+	os.WriteFile(filepath.Join(credReqsDir, "0000_50_cluster-image-registry-operator_01-registry-credentials-request.yaml"), []byte(`kind: CredentialsRequest
+spec:
+  secretRef:
+    name: installer-cloud-credentials
+    namespace: openshift-image-registry
+`), 0644)
+
+	executor := NewMockExecutor()
+	// dev-2 is another cluster whose roles share the dev- prefix
+	executor.SetOutput("aws iam list-roles --query Roles[].RoleName --output json", `[
+		"dev-2-openshift-image-registry-installer-cloud-credentials",
+		"dev-openshift-image-registry-installer-cloud-credentials",
+		"dev-x7k2p-master-role",
+		"dev-2-x7k2p-master-role"
+	]`)
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/d1234abcd.cloudfront.net"},
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/other-oidc.s3.us-east-2.amazonaws.com"}
+	]}`)
+	executor.SetError("aws s3api head-bucket --bucket dev-oidc --output json",
+		errors.New("An error occurred (404) when calling the HeadBucket operation: Not Found"))
+	executor.SetOutput("aws cloudfront list-distributions --output json", `{"DistributionList": {"Items": [
+		{"Id": "E2QWRUHAPOMQZL", "DomainName": "d1234abcd.cloudfront.net", "Origins": {"Items": [{"DomainName": "dev-oidc.s3.us-east-2.amazonaws.com"}]}},
+		{"Id": "EOTHER", "DomainName": "d9999.cloudfront.net", "Origins": {"Items": [{"DomainName": "other.s3.us-east-2.amazonaws.com"}]}}
+	]}}`)
+	executor.SetOutput("aws resourcegroupstaggingapi get-resources --region us-east-2 --tag-filters Key=kubernetes.io/cluster/dev-x7k2p --output json",
+		`{"ResourceTagMappingList": [{"ResourceARN": "arn:aws:ec2:us-east-2:123456789012:vpc/vpc-0abc"}]}`)
+
+	orphans, err := FindOrphans(executor, nil, nil, installation, true)
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+
+	want := []Orphan{
+		{Kind: OrphanIAMRole, ID: "dev-openshift-image-registry-installer-cloud-credentials"},
+		{Kind: OrphanIAMRole, ID: "dev-x7k2p-master-role"},
+		{Kind: OrphanOIDCProvider, ID: "arn:aws:iam::123456789012:oidc-provider/d1234abcd.cloudfront.net"},
+		{Kind: OrphanCloudFrontDistribution, ID: "E2QWRUHAPOMQZL"},
+		{Kind: OrphanTaggedResource, ID: "arn:aws:ec2:us-east-2:123456789012:vpc/vpc-0abc"},
+	}
+	if len(orphans) != len(want) {
+		t.Fatalf("Expected %d orphans, got %+v", len(want), orphans)
+	}
+	for i, orphan := range orphans {
+		if orphan.Kind != want[i].Kind || orphan.ID != want[i].ID {
+			t.Errorf("Expected %s %s, got %s %s", want[i].Kind, want[i].ID, orphan.Kind, orphan.ID)
+		}
+		if len(orphan.Remove) == 0 {
+			t.Errorf("Expected removal commands for %s", orphan.ID)
+		}
+	}
+
+	// Without metadata.json, destroy needs one naming the infraID
+	tagged := orphans[4].Remove
+	if !strings.Contains(tagged[0], `"infraID":"dev-x7k2p"`) || !strings.Contains(tagged[1], "destroy cluster --dir") {
+		t.Errorf("Unexpected removal commands %v", tagged)
+	}
+}
+
+func TestFindOrphansBYOIAM(t *testing.T) {
+	installation := &Installation{Workspace: t.TempDir(), VersionArch: "4.12.0-x86_64", ClusterName: "dev", Region: "us-east-2"}
+	executor := NewMockExecutor()
+	executor.SetError("aws s3api head-bucket --bucket dev-oidc --output json", errors.New("An error occurred (403) when calling the HeadBucket operation: Forbidden"))
+
+	orphans, err := FindOrphans(executor, nil, nil, installation, false)
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+	if len(orphans) != 0 || len(executor.Commands) != 0 {
+		t.Errorf("Expected no query without IAM resources and infraID, got %v", executor.Commands)
+	}
+
+	// A bucket we cannot look up is an error, not a missing bucket
+	if _, err := FindOrphans(executor, nil, nil, installation, true); err == nil {
+		t.Error("Expected an error when head-bucket is forbidden")
+	}
+}
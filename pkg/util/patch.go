@@ -0,0 +1,222 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is a single RFC 6902 JSON patch operation
+type PatchOperation struct {
+	Op    string      `yaml:"op"`
+	Path  string      `yaml:"path"`
+	From  string      `yaml:"from"`
+	Value interface{} `yaml:"value"`
+}
+
+// ApplyJSONPatch applies RFC 6902 operations to a document decoded from YAML or JSON
+func ApplyJSONPatch(doc interface{}, ops []PatchOperation) (interface{}, error) {
+	var err error
+	for _, op := range ops {
+		switch op.Op {
+		case "add":
+			doc, err = pointerSet(doc, op.Path, op.Value, true)
+		case "replace":
+			if _, err = pointerGet(doc, op.Path); err == nil {
+				doc, err = pointerSet(doc, op.Path, op.Value, false)
+			}
+		case "remove":
+			doc, _, err = pointerRemove(doc, op.Path)
+		case "move":
+			var value interface{}
+			if doc, value, err = pointerRemove(doc, op.From); err == nil {
+				doc, err = pointerSet(doc, op.Path, value, true)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = pointerGet(doc, op.From); err == nil {
+				doc, err = pointerSet(doc, op.Path, value, true)
+			}
+		case "test":
+			var value interface{}
+			if value, err = pointerGet(doc, op.Path); err == nil && !reflect.DeepEqual(value, op.Value) {
+				err = fmt.Errorf("value at %s does not match", op.Path)
+			}
+		default:
+			err = fmt.Errorf("unsupported operation %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %s %s failed: %w", op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s not found", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("invalid index %q in %s", token, pointer)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %s not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// pointerSet sets the value at pointer. With insert, list indexes insert
+// before the element (and "-" appends) instead of replacing it.
+func pointerSet(doc interface{}, pointer string, value interface{}, insert bool) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		var updated []interface{}
+		if last == "-" && insert {
+			updated = append(node, value)
+		} else {
+			index, err := strconv.Atoi(last)
+			if err != nil || index < 0 || index > len(node) || (!insert && index == len(node)) {
+				return nil, fmt.Errorf("invalid index %q in %s", last, pointer)
+			}
+			if insert {
+				updated = append(append(append([]interface{}{}, node[:index]...), value), node[index:]...)
+			} else {
+				node[index] = value
+				updated = node
+			}
+		}
+		return pointerSet(doc, parentPointer, updated, false)
+	default:
+		return nil, fmt.Errorf("path %s not found", parentPointer)
+	}
+}
+
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	value, err := pointerGet(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, _ := splitPointer(pointer)
+	if len(tokens) == 0 {
+		return nil, value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, _ := pointerGet(doc, parentPointer)
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		index, _ := strconv.Atoi(last)
+		updated := append(append([]interface{}{}, node[:index]...), node[index+1:]...)
+		doc, err = pointerSet(doc, parentPointer, updated, false)
+		return doc, value, err
+	}
+	return nil, nil, fmt.Errorf("path %s not found", pointer)
+}
+
+// StrategicMerge merges patch into doc the way kubectl merges a strategic
+// merge patch for common manifests: maps are merged recursively, null removes
+// a key, lists of objects with a "name" field are merged by name and any other
+// list is replaced.
+func StrategicMerge(doc interface{}, patch interface{}) interface{} {
+	docMap, docIsMap := doc.(map[string]interface{})
+	patchMap, patchIsMap := patch.(map[string]interface{})
+	if docIsMap && patchIsMap {
+		for key, value := range patchMap {
+			if value == nil {
+				delete(docMap, key)
+				continue
+			}
+			if existing, ok := docMap[key]; ok {
+				docMap[key] = StrategicMerge(existing, value)
+			} else {
+				docMap[key] = value
+			}
+		}
+		return docMap
+	}
+
+	docList, docIsList := doc.([]interface{})
+	patchList, patchIsList := patch.([]interface{})
+	if docIsList && patchIsList && namedList(docList) && namedList(patchList) {
+		for _, item := range patchList {
+			name := item.(map[string]interface{})["name"]
+			merged := false
+			for i, existing := range docList {
+				if existing.(map[string]interface{})["name"] == name {
+					docList[i] = StrategicMerge(existing, item)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				docList = append(docList, item)
+			}
+		}
+		return docList
+	}
+
+	return patch
+}
+
+func namedList(list []interface{}) bool {
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"]; !ok {
+			return false
+		}
+	}
+	return len(list) > 0
+}
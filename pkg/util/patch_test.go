@@ -0,0 +1,122 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func decodeYAML(t *testing.T, content string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+	return doc
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := decodeYAML(t, `
+metadata:
+  name: cluster
+  labels:
+    a: "1"
+spec:
+  items: [x, y]
+`)
+	ops := []PatchOperation{
+		{Op: "add", Path: "/metadata/labels/b", Value: "2"},
+		{Op: "replace", Path: "/metadata/name", Value: "other"},
+		{Op: "remove", Path: "/metadata/labels/a"},
+		{Op: "add", Path: "/spec/items/-", Value: "z"},
+		{Op: "add", Path: "/spec/items/0", Value: "w"},
+		{Op: "copy", From: "/metadata/name", Path: "/spec/name"},
+		{Op: "test", Path: "/spec/name", Value: "other"},
+	}
+
+	result, err := ApplyJSONPatch(doc, ops)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := decodeYAML(t, `
+metadata:
+  name: other
+  labels:
+    b: "2"
+spec:
+  name: other
+  items: [w, x, y, z]
+`)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result:\n%v\nexpected:\n%v", result, expected)
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		op   PatchOperation
+	}{
+		{"replace missing key", PatchOperation{Op: "replace", Path: "/missing", Value: 1}},
+		{"remove missing key", PatchOperation{Op: "remove", Path: "/missing"}},
+		{"failed test", PatchOperation{Op: "test", Path: "/name", Value: "other"}},
+		{"unknown op", PatchOperation{Op: "frobnicate", Path: "/name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decodeYAML(t, "name: cluster\n")
+			if _, err := ApplyJSONPatch(doc, []PatchOperation{tt.op}); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestStrategicMerge(t *testing.T) {
+	doc := decodeYAML(t, `
+metadata:
+  name: cluster
+  annotations:
+    drop: me
+spec:
+  containers:
+  - name: app
+    image: app:1
+  - name: sidecar
+    image: sidecar:1
+  args: [a, b]
+`)
+	patch := decodeYAML(t, `
+metadata:
+  annotations:
+    drop: null
+    keep: me
+spec:
+  containers:
+  - name: app
+    image: app:2
+  args: [c]
+`)
+
+	result := StrategicMerge(doc, patch)
+
+	expected := decodeYAML(t, `
+metadata:
+  name: cluster
+  annotations:
+    keep: me
+spec:
+  containers:
+  - name: app
+    image: app:2
+  - name: sidecar
+    image: sidecar:1
+  args: [c]
+`)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result:\n%v\nexpected:\n%v", result, expected)
+	}
+}
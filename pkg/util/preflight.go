@@ -0,0 +1,491 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PreflightInput describes the cluster the preflight checks run for
+type PreflightInput struct {
+	ClusterName   string
+	Region        string
+	InstallConfig *InstallConfig
+	// DefaultInstanceType is used for machine pools without platform.aws.type
+	DefaultInstanceType string
+	// WithOIDC is false with byoIAM, when Step 7 creates no bucket or OIDC provider
+	WithOIDC bool
+	// PrivateBucket is true when the issuer is a CloudFront distribution in
+	// front of the bucket
+	PrivateBucket bool
+	// OwnProviderARN is the OIDC provider a previous Step 7 run created
+	// together with the bucket; resuming Step 7 needs both, they do not collide
+	OwnProviderARN string
+	// IAMEnv and DNSEnv are the environments of the IAM and DNS profiles,
+	// for the name collisions and the hosted zone. Nil uses the infra one.
+	IAMEnv []string
+	DNSEnv []string
+}
+
+// PreflightFinding is a missing prerequisite of the installation
+type PreflightFinding struct {
+	Check   string
+	Message string
+}
+
+// Preflight checks
+const (
+	PreflightRoute53       = "Route53 zone"
+	PreflightQuota         = "quota"
+	PreflightOffering      = "instance offering"
+	PreflightNameCollision = "name collision"
+)
+
+// defaultReplicas is the openshift-install default of a machine pool
+const defaultReplicas = 3
+
+// serviceQuota is a regional AWS service quota
+type serviceQuota struct {
+	Name        string
+	ServiceCode string
+	QuotaCode   string
+}
+
+var (
+	quotaStandardVCPUs = serviceQuota{"Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances vCPUs", "ec2", "L-1216C47A"}
+	quotaElasticIPs    = serviceQuota{"EC2-VPC Elastic IPs", "ec2", "L-0263D0A3"}
+	quotaVPCs          = serviceQuota{"VPCs per Region", "vpc", "L-F678F1CE"}
+	quotaNLBs          = serviceQuota{"Network Load Balancers per Region", "elasticloadbalancing", "L-69A177A2"}
+)
+
+// standardInstanceFamilies are the instance families counted against the
+// standard vCPU quota, by their first letter
+const standardInstanceFamilies = "acdhimrtz"
+
+// preflightPool is a machine pool with the defaults of openshift-install applied
+type preflightPool struct {
+	Name         string
+	Replicas     int
+	InstanceType string
+	Zones        []string
+}
+
+// RunPreflight checks through the aws CLI the prerequisites Steps 7 and 10
+// otherwise fail on late: the public Route53 zone of the base domain, the
+// EC2, VPC and NLB quotas, the instance type offerings and the names Step 7
+// creates. It returns the missing prerequisites, and an error when a check
+// could not run.
+func RunPreflight(executor CommandExecutor, env []string, input PreflightInput) ([]PreflightFinding, error) {
+	pools := preflightPools(input)
+	zones, err := availabilityZones(executor, env, input.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	iamEnv, dnsEnv := env, env
+	if input.IAMEnv != nil {
+		iamEnv = input.IAMEnv
+	}
+	if input.DNSEnv != nil {
+		dnsEnv = input.DNSEnv
+	}
+
+	var findings []PreflightFinding
+	for _, check := range []func() ([]PreflightFinding, error){
+		func() ([]PreflightFinding, error) { return checkRoute53Zone(executor, dnsEnv, input) },
+		func() ([]PreflightFinding, error) { return checkQuotas(executor, env, input, pools, zones) },
+		func() ([]PreflightFinding, error) { return checkOfferings(executor, env, input.Region, pools) },
+		func() ([]PreflightFinding, error) {
+			if !input.WithOIDC {
+				return nil, nil
+			}
+			return checkNameCollisions(executor, iamEnv, input)
+		},
+	} {
+		found, err := check()
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
+
+func preflightPools(input PreflightInput) []preflightPool {
+	var pools []preflightPool
+	for _, pool := range input.InstallConfig.MachinePools() {
+		p := preflightPool{
+			Name:         pool.Name,
+			Replicas:     defaultReplicas,
+			InstanceType: pool.Platform.AWS.Type,
+			Zones:        pool.Platform.AWS.Zones,
+		}
+		if pool.Replicas != nil {
+			p.Replicas = *pool.Replicas
+		}
+		if p.InstanceType == "" {
+			p.InstanceType = input.DefaultInstanceType
+		}
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// availabilityZones lists the availability zones of the region
+func availabilityZones(executor CommandExecutor, env []string, region string) ([]string, error) {
+	output, err := RunAWS(executor, env, "ec2", "describe-availability-zones", "--region", region,
+		"--filters", "Name=zone-type,Values=availability-zone", "--query", "AvailabilityZones[].ZoneName")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the availability zones of %s: %w", region, err)
+	}
+	var zones []string
+	if err := parseAWSOutput(output, &zones); err != nil {
+		return nil, fmt.Errorf("failed to parse aws ec2 describe-availability-zones output: %w", err)
+	}
+	return zones, nil
+}
+
+// checkRoute53Zone checks that the base domain has a public hosted zone,
+// which an Internal cluster does not need
+func checkRoute53Zone(executor CommandExecutor, env []string, input PreflightInput) ([]PreflightFinding, error) {
+	baseDomain := strings.TrimSuffix(input.InstallConfig.BaseDomain, ".")
+	if input.InstallConfig.Publish == "Internal" || baseDomain == "" {
+		return nil, nil
+	}
+	zones, err := hostedZones(executor, env, baseDomain)
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		if !zone.Config.PrivateZone {
+			return nil, nil
+		}
+	}
+	return []PreflightFinding{{PreflightRoute53, fmt.Sprintf("no public Route53 hosted zone found for the base domain %s", baseDomain)}}, nil
+}
+
+type hostedZone struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		PrivateZone bool `json:"PrivateZone"`
+	} `json:"Config"`
+}
+
+// hostedZones returns the Route53 hosted zones of exactly the base domain
+func hostedZones(executor CommandExecutor, env []string, baseDomain string) ([]hostedZone, error) {
+	output, err := RunAWS(executor, env, "route53", "list-hosted-zones-by-name", "--dns-name", baseDomain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the Route53 zones of %s: %w", baseDomain, err)
+	}
+	var parsed struct {
+		HostedZones []hostedZone `json:"HostedZones"`
+	}
+	if err := parseAWSOutput(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws route53 list-hosted-zones-by-name output: %w", err)
+	}
+	var zones []hostedZone
+	for _, zone := range parsed.HostedZones {
+		if zone.Name == baseDomain+"." {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// FindPrivateHostedZone returns the ID of the only private hosted zone of
+// the base domain, as platform.aws.hostedZone expects it
+func FindPrivateHostedZone(executor CommandExecutor, env []string, baseDomain string) (string, error) {
+	baseDomain = strings.TrimSuffix(baseDomain, ".")
+	zones, err := hostedZones(executor, env, baseDomain)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, zone := range zones {
+		if zone.Config.PrivateZone {
+			ids = append(ids, strings.TrimPrefix(zone.ID, "/hostedzone/"))
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("no private hosted zone found for %s", baseDomain)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%d private hosted zones found for %s (%s)", len(ids), baseDomain, strings.Join(ids, ", "))
+}
+
+// checkQuotas checks that what the cluster creates fits in what the quotas
+// leave: the vCPUs of the machine pools, one Elastic IP per zone for the NAT
+// gateways and the VPC, unless installing into existing subnets, and the API
+// load balancers
+func checkQuotas(executor CommandExecutor, env []string, input PreflightInput, pools []preflightPool, zones []string) ([]PreflightFinding, error) {
+	vcpus, err := requiredVCPUs(executor, env, input.Region, pools)
+	if err != nil {
+		return nil, err
+	}
+	type requirement struct {
+		quota    serviceQuota
+		required int
+		inUse    []string
+	}
+	requirements := []requirement{{quotaStandardVCPUs, vcpus, nil}}
+	if len(input.InstallConfig.Platform.AWS.Subnets) == 0 {
+		requirements = append(requirements,
+			requirement{quotaElasticIPs, len(usedZones(pools, zones)),
+				[]string{"ec2", "describe-addresses", "--region", input.Region, "--query", "length(Addresses)"}},
+			requirement{quotaVPCs, 1,
+				[]string{"ec2", "describe-vpcs", "--region", input.Region, "--query", "length(Vpcs)"}})
+	}
+	loadBalancers := 2
+	if input.InstallConfig.Publish == "Internal" {
+		loadBalancers = 1
+	}
+	requirements = append(requirements, requirement{quotaNLBs, loadBalancers,
+		[]string{"elbv2", "describe-load-balancers", "--region", input.Region, "--query", "length(LoadBalancers[?Type=='network'])"}})
+
+	var findings []PreflightFinding
+	for _, r := range requirements {
+		if r.required == 0 {
+			continue
+		}
+		limit, err := quotaValue(executor, env, input.Region, r.quota)
+		if err != nil {
+			return nil, err
+		}
+		var inUse int
+		if r.inUse == nil {
+			inUse, err = standardVCPUsInUse(executor, env, input.Region)
+		} else {
+			inUse, err = countInUse(executor, env, r.inUse)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if inUse+r.required > limit {
+			findings = append(findings, PreflightFinding{PreflightQuota, fmt.Sprintf(
+				"%s (%s/%s): the cluster needs %d, %d of %d are in use in %s",
+				r.quota.Name, r.quota.ServiceCode, r.quota.QuotaCode, r.required, inUse, limit, input.Region)})
+		}
+	}
+	return findings, nil
+}
+
+// requiredVCPUs sums the vCPUs of the machine pools in standard instance families
+func requiredVCPUs(executor CommandExecutor, env []string, region string, pools []preflightPool) (int, error) {
+	var types []string
+	for _, pool := range pools {
+		if isStandardInstanceType(pool.InstanceType) && !slices.Contains(types, pool.InstanceType) {
+			types = append(types, pool.InstanceType)
+		}
+	}
+	if len(types) == 0 {
+		return 0, nil
+	}
+	args := append([]string{"ec2", "describe-instance-types", "--region", region, "--instance-types"}, types...)
+	output, err := RunAWS(executor, env, append(args, "--query", "InstanceTypes[].[InstanceType,VCpuInfo.DefaultVCpus]")...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe instance types %s: %w", strings.Join(types, ", "), err)
+	}
+	var rows [][]interface{}
+	if err := parseAWSOutput(output, &rows); err != nil {
+		return 0, fmt.Errorf("failed to parse aws ec2 describe-instance-types output: %w", err)
+	}
+	perType := map[string]int{}
+	for _, row := range rows {
+		if len(row) == 2 {
+			name, _ := row[0].(string)
+			count, _ := row[1].(float64)
+			perType[name] = int(count)
+		}
+	}
+
+	vcpus := 0
+	for _, pool := range pools {
+		vcpus += pool.Replicas * perType[pool.InstanceType]
+	}
+	return vcpus, nil
+}
+
+// standardVCPUsInUse sums the vCPUs of the pending and running instances in
+// standard instance families
+func standardVCPUsInUse(executor CommandExecutor, env []string, region string) (int, error) {
+	output, err := RunAWS(executor, env, "ec2", "describe-instances", "--region", region,
+		"--filters", "Name=instance-state-name,Values=pending,running",
+		"--query", "Reservations[].Instances[].[InstanceType,CpuOptions.CoreCount,CpuOptions.ThreadsPerCore]")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the instances of %s: %w", region, err)
+	}
+	var rows [][]interface{}
+	if err := parseAWSOutput(output, &rows); err != nil {
+		return 0, fmt.Errorf("failed to parse aws ec2 describe-instances output: %w", err)
+	}
+	vcpus := 0
+	for _, row := range rows {
+		if len(row) != 3 {
+			continue
+		}
+		instanceType, _ := row[0].(string)
+		cores, _ := row[1].(float64)
+		threads, _ := row[2].(float64)
+		if isStandardInstanceType(instanceType) {
+			vcpus += int(cores * threads)
+		}
+	}
+	return vcpus, nil
+}
+
+// countInUse runs an aws query returning a number
+func countInUse(executor CommandExecutor, env []string, args []string) (int, error) {
+	output, err := RunAWS(executor, env, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run aws %s: %w", strings.Join(args[:2], " "), err)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse aws %s output %q", strings.Join(args[:2], " "), output)
+	}
+	return count, nil
+}
+
+// quotaValue returns the applied value of a quota, or its default when it
+// was never changed for the account
+func quotaValue(executor CommandExecutor, env []string, region string, quota serviceQuota) (int, error) {
+	args := []string{"--region", region, "--service-code", quota.ServiceCode, "--quota-code", quota.QuotaCode, "--query", "Quota.Value"}
+	output, err := RunAWS(executor, env, append([]string{"service-quotas", "get-service-quota"}, args...)...)
+	if err != nil && strings.Contains(err.Error(), "NoSuchResourceException") {
+		output, err = RunAWS(executor, env, append([]string{"service-quotas", "get-aws-default-service-quota"}, args...)...)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the quota %s: %w", quota.Name, err)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the quota %s %q", quota.Name, output)
+	}
+	return int(value), nil
+}
+
+// checkOfferings checks that every instance type is offered in the zones of
+// its pool, or in some zone of the region when the pool sets no zones
+func checkOfferings(executor CommandExecutor, env []string, region string, pools []preflightPool) ([]PreflightFinding, error) {
+	offered := map[string][]string{}
+	var findings []PreflightFinding
+	for _, pool := range pools {
+		zones, ok := offered[pool.InstanceType]
+		if !ok {
+			output, err := RunAWS(executor, env, "ec2", "describe-instance-type-offerings", "--region", region,
+				"--location-type", "availability-zone", "--filters", "Name=instance-type,Values="+pool.InstanceType,
+				"--query", "InstanceTypeOfferings[].Location")
+			if err != nil {
+				return nil, fmt.Errorf("failed to list the offerings of %s: %w", pool.InstanceType, err)
+			}
+			if err := parseAWSOutput(output, &zones); err != nil {
+				return nil, fmt.Errorf("failed to parse aws ec2 describe-instance-type-offerings output: %w", err)
+			}
+			offered[pool.InstanceType] = zones
+		}
+
+		if len(pool.Zones) == 0 && len(zones) == 0 {
+			findings = append(findings, PreflightFinding{PreflightOffering,
+				fmt.Sprintf("%s of machine pool %s is not offered in %s", pool.InstanceType, pool.Name, region)})
+		}
+		var missing []string
+		for _, zone := range pool.Zones {
+			if !slices.Contains(zones, zone) {
+				missing = append(missing, zone)
+			}
+		}
+		if len(missing) > 0 {
+			findings = append(findings, PreflightFinding{PreflightOffering,
+				fmt.Sprintf("%s of machine pool %s is not offered in %s", pool.InstanceType, pool.Name, strings.Join(missing, ", "))})
+		}
+	}
+	return findings, nil
+}
+
+// checkNameCollisions checks that the OIDC bucket and provider Step 7 names
+// after the cluster do not exist yet. Bucket names are global: a bucket of
+// another account collides too.
+func checkNameCollisions(executor CommandExecutor, env []string, input PreflightInput) ([]PreflightFinding, error) {
+	var findings []PreflightFinding
+	bucket := OIDCBucketName(input.ClusterName)
+	// The run being resumed created the bucket together with the provider
+	if input.OwnProviderARN == "" {
+		if _, err := RunAWS(executor, env, "s3api", "head-bucket", "--bucket", bucket); err == nil {
+			findings = append(findings, PreflightFinding{PreflightNameCollision,
+				fmt.Sprintf("S3 bucket %s already exists, left over by a previous attempt? See the cleanup command", bucket)})
+		} else if strings.Contains(err.Error(), "(403)") || strings.Contains(err.Error(), "Forbidden") {
+			findings = append(findings, PreflightFinding{PreflightNameCollision,
+				fmt.Sprintf("S3 bucket %s already exists in another account, choose another cluster name", bucket)})
+		} else if !strings.Contains(err.Error(), "(404)") && !strings.Contains(err.Error(), "Not Found") {
+			return nil, fmt.Errorf("failed to look up S3 bucket %s: %w", bucket, err)
+		}
+	}
+
+	output, err := RunAWS(executor, env, "iam", "list-open-id-connect-providers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OIDC providers: %w", err)
+	}
+	var parsed struct {
+		OpenIDConnectProviderList []struct {
+			Arn string `json:"Arn"`
+		} `json:"OpenIDConnectProviderList"`
+	}
+	if err := parseAWSOutput(output, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse aws iam list-open-id-connect-providers output: %w", err)
+	}
+	// A private bucket is served by a CloudFront distribution, whose domain
+	// is the issuer of a provider left by an earlier attempt
+	issuerHosts := []string{fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, input.Region)}
+	if input.PrivateBucket {
+		distributions, err := listDistributions(executor, env)
+		if err != nil {
+			return nil, err
+		}
+		issuerHosts = nil
+		for _, distribution := range distributions {
+			if distribution.servesBucket(bucket) {
+				issuerHosts = append(issuerHosts, distribution.DomainName)
+			}
+		}
+	}
+	for _, provider := range parsed.OpenIDConnectProviderList {
+		if provider.Arn == input.OwnProviderARN {
+			continue
+		}
+		for _, issuerHost := range issuerHosts {
+			if strings.HasSuffix(provider.Arn, ":oidc-provider/"+issuerHost) {
+				findings = append(findings, PreflightFinding{PreflightNameCollision,
+					fmt.Sprintf("OIDC provider %s already exists", provider.Arn)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// usedZones returns the zones of the pools, or every zone of the region when
+// a pool sets none, as openshift-install spreads it over all of them
+func usedZones(pools []preflightPool, regionZones []string) []string {
+	var zones []string
+	for _, pool := range pools {
+		poolZones := pool.Zones
+		if len(poolZones) == 0 {
+			poolZones = regionZones
+		}
+		for _, zone := range poolZones {
+			if !slices.Contains(zones, zone) {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+func isStandardInstanceType(instanceType string) bool {
+	return instanceType != "" && strings.ContainsRune(standardInstanceFamilies, rune(strings.ToLower(instanceType)[0]))
+}
@@ -0,0 +1,178 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// preflightExecutor answers the preflight queries for a dev cluster in
+// us-east-2 with three zones, where every check passes
+func preflightExecutor() *MockExecutor {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-availability-zones --region us-east-2 --filters Name=zone-type,Values=availability-zone --query AvailabilityZones[].ZoneName --output json",
+		`["us-east-2a", "us-east-2b", "us-east-2c"]`)
+	executor.SetOutput("aws route53 list-hosted-zones-by-name --dns-name example.com --output json", `{"HostedZones": [
+		{"Name": "example.com.", "Config": {"PrivateZone": true}},
+		{"Name": "example.com.", "Config": {"PrivateZone": false}}
+	]}`)
+	executor.SetOutput("aws ec2 describe-instance-types --region us-east-2 --instance-types m5.xlarge m5.4xlarge --query InstanceTypes[].[InstanceType,VCpuInfo.DefaultVCpus] --output json",
+		`[["m5.4xlarge", 16], ["m5.xlarge", 4]]`)
+	executor.SetOutput("aws ec2 describe-instances --region us-east-2 --filters Name=instance-state-name,Values=pending,running --query Reservations[].Instances[].[InstanceType,CpuOptions.CoreCount,CpuOptions.ThreadsPerCore] --output json",
+		`[["m5.2xlarge", 4, 2], ["p3.2xlarge", 4, 2]]`)
+	for code, value := range map[string]string{"ec2 --quota-code L-1216C47A": "64.0", "ec2 --quota-code L-0263D0A3": "5.0", "vpc --quota-code L-F678F1CE": "5.0"} {
+		executor.SetOutput("aws service-quotas get-service-quota --region us-east-2 --service-code "+code+" --query Quota.Value --output json", value)
+	}
+	executor.SetError("aws service-quotas get-service-quota --region us-east-2 --service-code elasticloadbalancing --quota-code L-69A177A2 --query Quota.Value --output json",
+		errors.New("An error occurred (NoSuchResourceException) when calling the GetServiceQuota operation"))
+	executor.SetOutput("aws service-quotas get-aws-default-service-quota --region us-east-2 --service-code elasticloadbalancing --quota-code L-69A177A2 --query Quota.Value --output json", "50.0")
+	executor.SetOutput("aws ec2 describe-addresses --region us-east-2 --query length(Addresses) --output json", "1")
+	executor.SetOutput("aws ec2 describe-vpcs --region us-east-2 --query length(Vpcs) --output json", "1")
+	executor.SetOutput("aws elbv2 describe-load-balancers --region us-east-2 --query length(LoadBalancers[?Type=='network']) --output json", "0")
+	for _, instanceType := range []string{"m5.xlarge", "m5.4xlarge"} {
+		executor.SetOutput("aws ec2 describe-instance-type-offerings --region us-east-2 --location-type availability-zone --filters Name=instance-type,Values="+instanceType+" --query InstanceTypeOfferings[].Location --output json",
+			`["us-east-2a", "us-east-2b", "us-east-2c"]`)
+	}
+	executor.SetError("aws s3api head-bucket --bucket dev-oidc --output json",
+		errors.New("An error occurred (404) when calling the HeadBucket operation: Not Found"))
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": []}`)
+	return executor
+}
+
+func preflightInput() PreflightInput {
+	three, two := 3, 2
+	installConfig := &InstallConfig{BaseDomain: "example.com"}
+	installConfig.ControlPlane.Replicas = &three
+	installConfig.ControlPlane.Platform.AWS.Type = "m5.xlarge"
+	worker := MachinePool{Name: "worker", Replicas: &two}
+	worker.Platform.AWS.Zones = []string{"us-east-2a", "us-east-2b"}
+	installConfig.Compute = []MachinePool{worker}
+	return PreflightInput{
+		ClusterName:         "dev",
+		Region:              "us-east-2",
+		InstallConfig:       installConfig,
+		DefaultInstanceType: "m5.4xlarge",
+		WithOIDC:            true,
+	}
+}
+
+func TestRunPreflight(t *testing.T) {
+	findings, err := RunPreflight(preflightExecutor(), nil, preflightInput())
+	if err != nil {
+		t.Fatalf("RunPreflight failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v", findings)
+	}
+}
+
+func TestRunPreflightFindings(t *testing.T) {
+	executor := preflightExecutor()
+	executor.SetOutput("aws route53 list-hosted-zones-by-name --dns-name example.com --output json",
+		`{"HostedZones": [{"Name": "example.com.", "Config": {"PrivateZone": true}}]}`)
+	// 3x4 + 2x16 = 44 vCPUs needed, 8 of the 48 available in use
+	executor.SetOutput("aws service-quotas get-service-quota --region us-east-2 --service-code ec2 --quota-code L-1216C47A --query Quota.Value --output json", "48.0")
+	// One Elastic IP per zone: the control plane spreads over all three
+	executor.SetOutput("aws ec2 describe-addresses --region us-east-2 --query length(Addresses) --output json", "3")
+	executor.SetOutput("aws ec2 describe-instance-type-offerings --region us-east-2 --location-type availability-zone --filters Name=instance-type,Values=m5.4xlarge --query InstanceTypeOfferings[].Location --output json",
+		`["us-east-2a", "us-east-2c"]`)
+	executor.SetError("aws s3api head-bucket --bucket dev-oidc --output json",
+		errors.New("An error occurred (403) when calling the HeadBucket operation: Forbidden"))
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/dev-oidc.s3.us-east-2.amazonaws.com"}
+	]}`)
+
+	findings, err := RunPreflight(executor, nil, preflightInput())
+	if err != nil {
+		t.Fatalf("RunPreflight failed: %v", err)
+	}
+	want := []struct{ check, message string }{
+		{PreflightRoute53, "example.com"},
+		{PreflightQuota, "L-1216C47A): the cluster needs 44, 8 of 48"},
+		{PreflightQuota, "L-0263D0A3): the cluster needs 3, 3 of 5"},
+		{PreflightOffering, "m5.4xlarge of machine pool worker is not offered in us-east-2b"},
+		{PreflightNameCollision, "dev-oidc already exists in another account"},
+		{PreflightNameCollision, "oidc-provider/dev-oidc.s3.us-east-2.amazonaws.com"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %+v", len(want), findings)
+	}
+	for i, finding := range findings {
+		if finding.Check != want[i].check || !strings.Contains(finding.Message, want[i].message) {
+			t.Errorf("Expected %s finding with %q, got %+v", want[i].check, want[i].message, finding)
+		}
+	}
+}
+
+func TestRunPreflightExistingVPC(t *testing.T) {
+	input := preflightInput()
+	input.InstallConfig.Publish = "Internal"
+	input.InstallConfig.Platform.AWS.Subnets = []string{"subnet-0abc"}
+	input.WithOIDC = false
+	executor := preflightExecutor()
+
+	if _, err := RunPreflight(executor, nil, input); err != nil {
+		t.Fatalf("RunPreflight failed: %v", err)
+	}
+	for _, command := range executor.Commands {
+		for _, skipped := range []string{"route53", "describe-addresses", "describe-vpcs", "head-bucket", "list-open-id-connect-providers"} {
+			if strings.Contains(command, skipped) {
+				t.Errorf("Expected no %s call for an internal cluster in an existing VPC, got %s", skipped, command)
+			}
+		}
+	}
+
+	// A check that cannot run is an error, not a finding
+	executor.SetError("aws elbv2 describe-load-balancers --region us-east-2 --query length(LoadBalancers[?Type=='network']) --output json",
+		errors.New("AccessDenied"))
+	if _, err := RunPreflight(executor, nil, input); err == nil {
+		t.Error("Expected an error when a check cannot run")
+	}
+}
+
+func TestRunPreflightResumedStep7(t *testing.T) {
+	executor := preflightExecutor()
+	// The bucket and provider a partial Step 7 created
+	delete(executor.Errors, "aws s3api head-bucket --bucket dev-oidc --output json")
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/dev-oidc.s3.us-east-2.amazonaws.com"}
+	]}`)
+
+	input := preflightInput()
+	input.OwnProviderARN = "arn:aws:iam::123456789012:oidc-provider/dev-oidc.s3.us-east-2.amazonaws.com"
+	findings, err := RunPreflight(executor, nil, input)
+	if err != nil {
+		t.Fatalf("RunPreflight failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Expected the resources of the resumed run not to collide, got %+v", findings)
+	}
+
+	// Without the recorded provider they are collisions
+	input.OwnProviderARN = ""
+	if findings, _ := RunPreflight(executor, nil, input); len(findings) != 2 {
+		t.Errorf("Expected 2 collisions, got %+v", findings)
+	}
+}
+
+func TestRunPreflightPrivateBucket(t *testing.T) {
+	executor := preflightExecutor()
+	executor.SetOutput("aws cloudfront list-distributions --output json", `{"DistributionList": {"Items": [
+		{"Id": "E2QWRUHAPOMQZL", "DomainName": "d1234abcd.cloudfront.net", "Origins": {"Items": [{"DomainName": "dev-oidc.s3.us-east-2.amazonaws.com"}]}},
+		{"Id": "E1OTHER", "DomainName": "d5678efgh.cloudfront.net", "Origins": {"Items": [{"DomainName": "other-oidc.s3.us-east-2.amazonaws.com"}]}}
+	]}}`)
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/d1234abcd.cloudfront.net"},
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/d5678efgh.cloudfront.net"}
+	]}`)
+
+	input := preflightInput()
+	input.PrivateBucket = true
+	findings, err := RunPreflight(executor, nil, input)
+	if err != nil {
+		t.Fatalf("RunPreflight failed: %v", err)
+	}
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "oidc-provider/d1234abcd.cloudfront.net") {
+		t.Errorf("Expected the provider behind the bucket distribution to collide, got %+v", findings)
+	}
+}
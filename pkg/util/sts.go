@@ -0,0 +1,228 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// STSEndpoint returns the STS endpoint for a region. AWS_ENDPOINT_URL_STS
+// (or AWS_ENDPOINT_URL) overrides it, e.g. for a local STS in tests.
+func STSEndpoint(region string) string {
+	for _, name := range []string{"AWS_ENDPOINT_URL_STS", "AWS_ENDPOINT_URL"} {
+		if endpoint := os.Getenv(name); endpoint != "" {
+			return strings.TrimSuffix(endpoint, "/")
+		}
+	}
+	if region == "" {
+		return "https://sts.amazonaws.com"
+	}
+	return fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+}
+
+// stsSigningRegion is the region STS requests are signed for, us-east-1 for
+// the global endpoint
+func stsSigningRegion(region string) string {
+	if region == "" {
+		return "us-east-1"
+	}
+	return region
+}
+
+// stsClient calls the STS query API
+type stsClient struct {
+	endpoint   string
+	region     string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// STSError is an error returned by the STS API
+type STSError struct {
+	Action  string
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *STSError) Error() string {
+	return fmt.Sprintf("STS %s failed: %s: %s", e.Action, e.Code, e.Message)
+}
+
+// call sends a signed STS action and decodes the XML response into result
+func (c *stsClient) call(creds *AWSCredentials, params url.Values, result interface{}) error {
+	params.Set("Version", "2011-06-15")
+	body := []byte(params.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signV4(req, body, creds, stsSigningRegion(c.region), "sts", c.now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("STS %s request failed: %w", params.Get("Action"), err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var parsed struct {
+			Error STSError `xml:"Error"`
+		}
+		if xml.Unmarshal(content, &parsed) == nil && parsed.Error.Code != "" {
+			parsed.Error.Action = params.Get("Action")
+			return &parsed.Error
+		}
+		return fmt.Errorf("STS %s failed with HTTP %d", params.Get("Action"), resp.StatusCode)
+	}
+	if err := xml.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("failed to parse STS %s response: %w", params.Get("Action"), err)
+	}
+	return nil
+}
+
+// assumeRoleInput holds the AssumeRole parameters of a profile
+type assumeRoleInput struct {
+	RoleARN         string
+	SessionName     string
+	ExternalID      string
+	DurationSeconds int
+	MFASerial       string
+	MFACode         string
+}
+
+// assumeRole exchanges the source credentials for temporary credentials of
+// the role
+func (c *stsClient) assumeRole(source *AWSCredentials, input assumeRoleInput) (*AWSCredentials, error) {
+	params := url.Values{}
+	params.Set("Action", "AssumeRole")
+	params.Set("RoleArn", input.RoleARN)
+	params.Set("RoleSessionName", input.SessionName)
+	if input.DurationSeconds > 0 {
+		params.Set("DurationSeconds", strconv.Itoa(input.DurationSeconds))
+	}
+	if input.ExternalID != "" {
+		params.Set("ExternalId", input.ExternalID)
+	}
+	if input.MFASerial != "" {
+		params.Set("SerialNumber", input.MFASerial)
+		params.Set("TokenCode", input.MFACode)
+	}
+
+	var response struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleResult>Credentials"`
+	}
+	if err := c.call(source, params, &response); err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", input.RoleARN, err)
+	}
+	return &AWSCredentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+		Expiration:      response.Credentials.Expiration,
+		Source:          CredentialSourceAssumeRole,
+	}, nil
+}
+
+// CallerIdentity is the identity credentials resolve to
+type CallerIdentity struct {
+	Account string `xml:"Account"`
+	ARN     string `xml:"Arn"`
+	UserID  string `xml:"UserId"`
+	// Expiration is the expiry of the credentials, zero for long-term keys
+	// and for a session token of unknown expiry
+	Expiration time.Time `xml:"-"`
+	// ExpiryUnknown is set for a session token without a known expiry
+	ExpiryUnknown bool `xml:"-"`
+}
+
+// getCallerIdentity returns the identity of the credentials
+func (c *stsClient) getCallerIdentity(creds *AWSCredentials) (*CallerIdentity, error) {
+	params := url.Values{}
+	params.Set("Action", "GetCallerIdentity")
+
+	var response struct {
+		Identity CallerIdentity `xml:"GetCallerIdentityResult"`
+	}
+	if err := c.call(creds, params, &response); err != nil {
+		return nil, err
+	}
+	response.Identity.Expiration = creds.Expiration
+	response.Identity.ExpiryUnknown = creds.ExpiryUnknown()
+	return &response.Identity, nil
+}
+
+// SSOEndpoint returns the IAM Identity Center portal endpoint for a region.
+// AWS_ENDPOINT_URL_SSO (or AWS_ENDPOINT_URL) overrides it.
+func SSOEndpoint(region string) string {
+	for _, name := range []string{"AWS_ENDPOINT_URL_SSO", "AWS_ENDPOINT_URL"} {
+		if endpoint := os.Getenv(name); endpoint != "" {
+			return strings.TrimSuffix(endpoint, "/")
+		}
+	}
+	return fmt.Sprintf("https://portal.sso.%s.amazonaws.com", region)
+}
+
+// ssoRoleCredentials exchanges a cached SSO access token for the
+// credentials of an account role
+func ssoRoleCredentials(httpClient *http.Client, endpoint, accessToken, accountID, roleName string) (*AWSCredentials, error) {
+	query := url.Values{}
+	query.Set("account_id", accountID)
+	query.Set("role_name", roleName)
+	req, err := http.NewRequest(http.MethodGet, endpoint+"/federation/credentials?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-amz-sso_bearer_token", accessToken)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SSO GetRoleCredentials request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SSO GetRoleCredentials failed with HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(content)))
+	}
+
+	var response struct {
+		RoleCredentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			// Expiration is in milliseconds since the epoch
+			Expiration int64 `json:"expiration"`
+		} `json:"roleCredentials"`
+	}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse SSO GetRoleCredentials response: %w", err)
+	}
+	return &AWSCredentials{
+		AccessKeyID:     response.RoleCredentials.AccessKeyID,
+		SecretAccessKey: response.RoleCredentials.SecretAccessKey,
+		SessionToken:    response.RoleCredentials.SessionToken,
+		Expiration:      time.UnixMilli(response.RoleCredentials.Expiration).UTC(),
+		Source:          CredentialSourceSSO,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAWSCredentials(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[valid]
+aws_access_key_id = AKIAVALID
+aws_secret_access_key = secret
+
+[expired]
+aws_access_key_id = ASIAEXPIRED
+aws_secret_access_key = secret
+aws_session_token = token
+
+[invalid]
+aws_access_key_id = AKIAINVALID
+aws_secret_access_key = secret
+`), 0600)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		params, _ := url.ParseQuery(string(body))
+		if params.Get("Action") != "GetCallerIdentity" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		auth := r.Header.Get("Authorization")
+		switch {
+		case strings.Contains(auth, "Credential=AKIAVALID/"):
+			fmt.Fprint(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>
+				<Arn>arn:aws:iam::123456789012:user/installer</Arn><UserId>AIDAEXAMPLE</UserId><Account>123456789012</Account>
+			</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+		case strings.Contains(auth, "Credential=ASIAEXPIRED/") && r.Header.Get("X-Amz-Security-Token") == "token":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>ExpiredToken</Code><Message>The security token included in the request is expired</Message></Error></ErrorResponse>`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	identity, err := ValidateAWSCredentials("valid")
+	if err != nil {
+		t.Fatalf("ValidateAWSCredentials failed: %v", err)
+	}
+	if identity.Account != "123456789012" || identity.ARN != "arn:aws:iam::123456789012:user/installer" || !identity.Expiration.IsZero() {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	if _, err := ValidateAWSCredentials("expired"); err == nil || !strings.Contains(err.Error(), "have expired") {
+		t.Errorf("Expected an expired error, got %v", err)
+	}
+	if _, err := ValidateAWSCredentials("invalid"); err == nil || !strings.Contains(err.Error(), "are invalid") {
+		t.Errorf("Expected an invalid error, got %v", err)
+	}
+	if _, err := ValidateAWSCredentials("missing"); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
+
+func TestValidateReportsExpiration(t *testing.T) {
+	home := awsTestHome(t)
+	os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(`[profile installer]
+credential_process = /bin/echo '{"Version":1,"AccessKeyId":"ASIAPROCESS","SecretAccessKey":"s","SessionToken":"t","Expiration":"2030-01-01T00:00:00Z"}'
+`), 0600)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<GetCallerIdentityResponse><GetCallerIdentityResult>
+			<Arn>arn:aws:sts::123456789012:assumed-role/Installer/session</Arn><Account>123456789012</Account>
+		</GetCallerIdentityResult></GetCallerIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	resolver := &AWSCredentialResolver{STSEndpoint: server.URL}
+	identity, err := resolver.Validate("installer")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !identity.Expiration.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) || identity.ExpiryUnknown {
+		t.Errorf("Expected the credential expiry, got %v", identity.Expiration)
+	}
+
+	// A session token without expiry is not a long-term key
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(`[session]
+aws_access_key_id = ASIASESSION
+aws_secret_access_key = secret
+aws_session_token = token
+`), 0600)
+	identity, err = resolver.Validate("session")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !identity.Expiration.IsZero() || !identity.ExpiryUnknown {
+		t.Errorf("Expected an unknown expiry, got %+v", identity)
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -26,3 +27,24 @@ func ExtractVersionArch(releaseImage string) (string, error) {
 
 	return tag, nil
 }
+
+// ParseMajorMinor extracts the major and minor version numbers from a version-arch string
+// Example: "4.12.0-x86_64" -> 4, 12
+func ParseMajorMinor(versionArch string) (major int, minor int, err error) {
+	parts := strings.SplitN(versionArch, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("cannot parse version from %q", versionArch)
+	}
+
+	major, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot parse major version from %q", versionArch)
+	}
+
+	minor, err = strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot parse minor version from %q", versionArch)
+	}
+
+	return major, minor, nil
+}
@@ -59,3 +59,35 @@ func TestExtractVersionArch(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMajorMinor(t *testing.T) {
+	tests := []struct {
+		versionArch   string
+		major, minor  int
+		shouldSucceed bool
+	}{
+		{"4.12.0-x86_64", 4, 12, true},
+		{"4.10.0-fc.4-x86_64", 4, 10, true},
+		{"4.16-x86_64", 4, 16, true},
+		{"latest", 0, 0, false},
+		{"four.12.0", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.versionArch, func(t *testing.T) {
+			major, minor, err := ParseMajorMinor(tt.versionArch)
+			if !tt.shouldSucceed {
+				if err == nil {
+					t.Error("Expected error but got success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+			if major != tt.major || minor != tt.minor {
+				t.Errorf("Expected %d.%d but got %d.%d", tt.major, tt.minor, major, minor)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Installation is an installation found in a workspace, described by its
+// install-config.yaml (or the backup taken before Step 6) and metadata.json
+type Installation struct {
+	Workspace   string
+	VersionArch string
+	ClusterName string
+	Region      string
+	InfraID     string
+	// Issuer is the service account issuer written by ccoctl
+	Issuer        string
+	PrivateBucket bool
+}
+
+// VersionDir returns the installation directory used by openshift-install
+func (i *Installation) VersionDir() string {
+	return filepath.Join(i.Workspace, "artifacts", i.VersionArch)
+}
+
+// HasInfrastructure reports whether openshift-install created infrastructure
+// that openshift-install destroy can find
+func (i *Installation) HasInfrastructure() bool {
+	return FileExists(filepath.Join(i.VersionDir(), "metadata.json")) ||
+		FileExists(filepath.Join(i.VersionDir(), ".openshift_install_state.json"))
+}
+
+// IssuerHost returns the host of the service account issuer, which is the
+// S3 bucket endpoint unless ccoctl wrote another issuer
+func (i *Installation) IssuerHost() string {
+	if i.Issuer != "" {
+		return strings.TrimPrefix(i.Issuer, "https://")
+	}
+	return fmt.Sprintf("%s.s3.%s.amazonaws.com", OIDCBucketName(i.ClusterName), i.Region)
+}
+
+// Binary returns the path of an extracted binary, falling back to the shared
+// artifacts/bin directory and then to $PATH
+func (i *Installation) Binary(name string) string {
+	for _, path := range []string{
+		filepath.Join(i.VersionDir(), "bin", name),
+		filepath.Join(i.Workspace, "artifacts", "bin", name),
+	} {
+		if FileExists(path) {
+			return path
+		}
+	}
+	return name
+}
+
+// FindInstallations returns the installations in the workspace, one per
+// version directory with an install-config.yaml, its backup or a metadata.json
+func FindInstallations(workspace string) ([]*Installation, error) {
+	dirs, err := filepath.Glob(filepath.Join(workspace, "artifacts", "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	var installations []*Installation
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() || filepath.Base(dir) == "bin" {
+			continue
+		}
+		installation := &Installation{Workspace: workspace, VersionArch: filepath.Base(dir)}
+
+		installConfigPath := filepath.Join(dir, "install-config.yaml")
+		for _, path := range []string{installConfigPath, installConfigPath + ".backup"} {
+			if name, region, err := ExtractClusterNameAndRegion(path); err == nil {
+				installation.ClusterName = name
+				installation.Region = region
+				break
+			}
+		}
+
+		if metadata, err := ReadClusterMetadata(filepath.Join(dir, "metadata.json")); err == nil {
+			installation.InfraID = metadata.InfraID
+			if installation.ClusterName == "" {
+				installation.ClusterName = metadata.ClusterName
+			}
+			if installation.Region == "" {
+				installation.Region = metadata.AWS.Region
+			}
+		}
+
+		if installation.ClusterName == "" {
+			continue
+		}
+		installation.Issuer = serviceAccountIssuer(filepath.Join(dir, "_output", "manifests", "cluster-authentication-02-config.yaml"))
+		// With a private bucket the issuer is served by CloudFront
+		installation.PrivateBucket = strings.Contains(installation.Issuer, ".cloudfront.net")
+		installations = append(installations, installation)
+	}
+	return installations, nil
+}
+
+// CcoctlIssuer returns the issuer in the authentication config that ccoctl
+// writes to its output directory, or "" before there is one
+func CcoctlIssuer(outputDir string) string {
+	return serviceAccountIssuer(filepath.Join(outputDir, "manifests", "cluster-authentication-02-config.yaml"))
+}
+
+// serviceAccountIssuer reads the issuer from the authentication config written by ccoctl
+func serviceAccountIssuer(authConfigPath string) string {
+	content, err := os.ReadFile(authConfigPath)
+	if err != nil {
+		return ""
+	}
+	var authentication struct {
+		Spec struct {
+			ServiceAccountIssuer string `yaml:"serviceAccountIssuer"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(content, &authentication); err != nil {
+		return ""
+	}
+	return authentication.Spec.ServiceAccountIssuer
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindInstallations(t *testing.T) {
+	workspace := t.TempDir()
+
+	// Step 6 consumed install-config.yaml, only the backup remains
+	first := filepath.Join(workspace, "artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(first, "bin"), 0755)
+	os.MkdirAll(filepath.Join(first, "_output", "manifests"), 0755)
+	os.WriteFile(filepath.Join(first, "install-config.yaml.backup"), []byte("metadata:\n  name: dev\nplatform:\n  aws:\n    region: us-east-2\n"), 0644)
+	os.WriteFile(filepath.Join(first, "metadata.json"), []byte(`{"clusterName":"dev","infraID":"dev-x7k2p","aws":{"region":"us-east-2"}}`), 0644)
+	os.WriteFile(filepath.Join(first, "_output", "manifests", "cluster-authentication-02-config.yaml"),
+		[]byte("spec:\n  serviceAccountIssuer: https://d1234abcd.cloudfront.net\n"), 0644)
+	os.WriteFile(filepath.Join(first, "bin", "ccoctl"), []byte{}, 0755)
+
+	second := filepath.Join(workspace, "artifacts", "4.13.0-x86_64")
+	os.MkdirAll(second, 0755)
+	os.WriteFile(filepath.Join(second, "install-config.yaml"), []byte("metadata:\n  name: test\nplatform:\n  aws:\n    region: eu-west-1\n"), 0644)
+
+	// Shared binaries and directories without an installation are ignored
+	os.MkdirAll(filepath.Join(workspace, "artifacts", "bin"), 0755)
+	os.WriteFile(filepath.Join(workspace, "artifacts", "bin", "openshift-install"), []byte{}, 0755)
+	os.MkdirAll(filepath.Join(workspace, "artifacts", "4.14.0-x86_64"), 0755)
+
+	installations, err := FindInstallations(workspace)
+	if err != nil {
+		t.Fatalf("FindInstallations failed: %v", err)
+	}
+	if len(installations) != 2 {
+		t.Fatalf("Expected 2 installations, got %d", len(installations))
+	}
+
+	dev := installations[0]
+	if dev.ClusterName != "dev" || dev.Region != "us-east-2" || dev.InfraID != "dev-x7k2p" || !dev.PrivateBucket {
+		t.Errorf("Unexpected installation %+v", dev)
+	}
+	if !dev.HasInfrastructure() {
+		t.Error("Expected infrastructure from metadata.json")
+	}
+	if dev.Binary("ccoctl") != filepath.Join(first, "bin", "ccoctl") {
+		t.Errorf("Expected versioned ccoctl, got %s", dev.Binary("ccoctl"))
+	}
+	if dev.Binary("openshift-install") != filepath.Join(workspace, "artifacts", "bin", "openshift-install") {
+		t.Errorf("Expected shared openshift-install, got %s", dev.Binary("openshift-install"))
+	}
+
+	test := installations[1]
+	if test.ClusterName != "test" || test.Region != "eu-west-1" || test.PrivateBucket || test.HasInfrastructure() {
+		t.Errorf("Unexpected installation %+v", test)
+	}
+}